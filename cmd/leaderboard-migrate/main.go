@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"go.uber.org/zap"
+
+	leaderboardDomain "pomocore-data/domains/leaderboard/domain"
+	appConfig "pomocore-data/infrastructure/config"
+	redisAdapter "pomocore-data/infrastructure/redis/adapter"
+	redisConfig "pomocore-data/infrastructure/redis/config"
+	envConfig "pomocore-data/shared/common/config"
+	"pomocore-data/shared/common/logger"
+)
+
+// leaderboard-migrate moves weekly and monthly boards from the v1 key scheme (2025-W5, 2025-M3)
+// to the zero-padded v2 scheme (2025-W05, 2025-03).
+//
+// Rollout: run the consumer with LEADERBOARD_KEY_SCHEMES=v1,v2, run this command in copy mode,
+// switch readers to v2, then set LEADERBOARD_KEY_SCHEMES=v2 and run again in delete mode.
+func main() {
+	envConfig.LoadEnv()
+
+	configFlags := appConfig.RegisterFlags()
+	mode := flag.String("mode", string(redisAdapter.MigrationModeCopy),
+		"copy replaces v2 boards with v1 boards, merge adds v1 scores to v2 boards, delete removes v1 boards")
+	dryRun := flag.Bool("dry-run", false, "only log the keys that would be migrated")
+	scanCount := flag.Int64("scan-count", 500, "SCAN batch size hint")
+	flag.Parse()
+	cfg := configFlags.Load()
+
+	if err := logger.InitLogger("pomocore-data-leaderboard-migrate", cfg.Environment); err != nil {
+		panic("Failed to initialize logger: " + err.Error())
+	}
+	defer logger.Sync()
+
+	redisClient, err := redisConfig.ConnectRedis(&cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis", logger.WithError(err))
+	}
+	defer redisClient.Close()
+
+	// Only the hash tag matters here; the migrator always reads v1 keys and writes v2 keys
+	leaderboardKeyLayout := leaderboardDomain.KeyLayout{
+		HashTag: cfg.Leaderboard.HashTag,
+	}
+
+	ctx := context.Background()
+
+	result, err := redisAdapter.NewLeaderboardKeyMigrator(redisClient, leaderboardKeyLayout).Migrate(ctx, redisAdapter.LeaderboardKeyMigrationOptions{
+		Mode:      redisAdapter.LeaderboardKeyMigrationMode(*mode),
+		DryRun:    *dryRun,
+		ScanCount: *scanCount,
+	})
+	if err != nil {
+		logger.Fatal("Leaderboard key migration failed", logger.WithError(err))
+	}
+
+	logger.Info("Leaderboard key migration finished",
+		zap.Int("scanned", result.Scanned),
+		zap.Int("migrated", result.Migrated),
+		zap.Int("deleted", result.Deleted),
+		zap.Bool("dry_run", *dryRun))
+}
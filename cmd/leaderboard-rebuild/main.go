@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"go.uber.org/zap"
+
+	categoryPatternService "pomocore-data/domains/categoryPattern/application/service"
+	leaderboardService "pomocore-data/domains/leaderboard/application/service"
+	leaderboardDomain "pomocore-data/domains/leaderboard/domain"
+	pomodoroService "pomocore-data/domains/pomodoro/application/service"
+	appConfig "pomocore-data/infrastructure/config"
+	mongoAdapter "pomocore-data/infrastructure/mongoDB/adapter"
+	mongoConfig "pomocore-data/infrastructure/mongoDB/config"
+	redisAdapter "pomocore-data/infrastructure/redis/adapter"
+	redisConfig "pomocore-data/infrastructure/redis/config"
+	envConfig "pomocore-data/shared/common/config"
+	"pomocore-data/shared/common/logger"
+)
+
+// leaderboard-rebuild recomputes leaderboards from pomodoro_usage_log and usage_credit, e.g. to backfill the
+// yearly and all-time boards. Boards are replaced as a whole, so increments made by a running consumer during
+// the rebuild are lost; stop the stream consumers before running it and start them again afterwards.
+// Only boards the rebuild computes scores for are replaced; boards left without any usage, e.g. of a category no
+// usage log belongs to anymore, keep their old scores and have to be deleted by hand.
+func main() {
+	envConfig.LoadEnv()
+
+	configFlags := appConfig.RegisterFlags()
+	periodsFlag := flag.String("periods", "yearly,all_time", "comma separated periods to rebuild: daily, weekly, monthly, yearly, all_time")
+	dryRun := flag.Bool("dry-run", false, "compute totals without writing to Redis")
+	flag.Parse()
+	cfg := configFlags.Load()
+
+	if err := logger.InitLogger("pomocore-data-leaderboard-rebuild", cfg.Environment); err != nil {
+		panic("Failed to initialize logger: " + err.Error())
+	}
+	defer logger.Sync()
+
+	periods, err := leaderboardDomain.ParsePeriods(*periodsFlag)
+	if err != nil {
+		logger.Fatal("Invalid periods", logger.WithError(err))
+	}
+
+	// Both were checked when the configuration was validated
+	bucketing, _ := cfg.Leaderboard.Bucketing()
+	leaderboardKeyLayout, _ := cfg.Leaderboard.KeyLayout()
+
+	mongoClient, err := mongoConfig.ConnectMongoDB(&cfg.Mongo)
+	if err != nil {
+		logger.Fatal("Failed to connect to MongoDB", logger.WithError(err))
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	db := mongoClient.Database(cfg.Mongo.Database)
+
+	redisClient, err := redisConfig.ConnectRedis(&cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis", logger.WithError(err))
+	}
+	defer redisClient.Close()
+
+	ctx := context.Background()
+
+	rebuildUseCase := pomodoroService.NewLeaderboardRebuildService(
+		mongoAdapter.NewPomodoroUsageLogRepositoryPort(db),
+		mongoAdapter.NewUsageCreditRepositoryPort(db),
+		categoryPatternService.NewCategoryPatternService(mongoAdapter.NewCategoryPatternRepositoryPort(db)),
+		redisAdapter.NewLeaderboardCachePort(
+			redisClient,
+			leaderboardKeyLayout,
+			cfg.Leaderboard.DedupeTTL,
+		),
+		leaderboardService.NewGroupMembershipCache(mongoAdapter.NewGroupMembershipRepositoryPort(db)),
+		bucketing,
+		leaderboardKeyLayout,
+	)
+
+	result, err := rebuildUseCase.Rebuild(ctx, periods, *dryRun)
+	if err != nil {
+		logger.Fatal("Leaderboard rebuild failed", logger.WithError(err))
+	}
+
+	logger.Info("Leaderboard rebuild finished",
+		zap.Strings("periods", periodNames(periods)),
+		zap.Int("usage_logs", result.UsageLogs),
+		zap.Int("stored_credits", result.StoredCredits),
+		zap.Int("boards", result.Boards),
+		zap.Bool("dry_run", *dryRun))
+}
+
+func periodNames(periods []leaderboardDomain.Period) []string {
+	names := make([]string, len(periods))
+	for i, period := range periods {
+		names[i] = string(period)
+	}
+	return names
+}
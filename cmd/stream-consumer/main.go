@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	categoryPatternService "pomocore-data/domains/categoryPattern/application/service"
+	leaderboardService "pomocore-data/domains/leaderboard/application/service"
 	"pomocore-data/domains/patternClassifier/domain/core"
 	pomodoroService "pomocore-data/domains/pomodoro/application/service"
+	appConfig "pomocore-data/infrastructure/config"
+	"pomocore-data/infrastructure/http/handler"
+	"pomocore-data/infrastructure/http/server"
+	"pomocore-data/infrastructure/http/webhook"
 	mongoAdapter "pomocore-data/infrastructure/mongoDB/adapter"
 	mongoConfig "pomocore-data/infrastructure/mongoDB/config"
 	"pomocore-data/infrastructure/mongoDB/model"
@@ -18,52 +26,57 @@ import (
 	"pomocore-data/infrastructure/redis/consumer"
 	envConfig "pomocore-data/shared/common/config"
 	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+	"pomocore-data/shared/common/scheduler"
+	"pomocore-data/shared/common/tracing"
 
-	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.uber.org/zap"
 )
 
 func main() {
 	envConfig.LoadEnv()
 
+	configFlags := appConfig.RegisterFlags()
+	flag.Parse()
+	cfg := configFlags.Load()
+
 	// Initialize logger
-	if err := logger.InitFromEnv("pomocore-data"); err != nil {
+	if err := logger.InitLogger("pomocore-data", cfg.Environment); err != nil {
 		panic("Failed to initialize logger: " + err.Error())
 	}
 	defer logger.Sync()
 
-	loc, err := time.LoadLocation("Asia/Seoul")
+	shutdownTracing, err := tracing.Init("pomocore-data", cfg.Tracing.Exporter)
 	if err != nil {
-		logger.Fatal("Failed to load timezone", logger.WithError(err))
+		logger.Fatal("Failed to initialize tracing", logger.WithError(err))
 	}
-	time.Local = loc
+
+	// Leaderboard days are bucketed explicitly instead of relying on time.Local.
+	// Both were checked when the configuration was validated.
+	bucketing, _ := cfg.Leaderboard.Bucketing()
+	leaderboardKeyLayout, _ := cfg.Leaderboard.KeyLayout()
 
 	// Initialize MongoDB
-	mongoClient, err := mongoConfig.ConnectMongoDB()
+	mongoClient, err := mongoConfig.ConnectMongoDB(&cfg.Mongo)
 	if err != nil {
 		logger.Fatal("Failed to connect to MongoDB", logger.WithError(err))
 	}
 	defer mongoClient.Disconnect(context.Background())
 
-	db := mongoClient.Database(envConfig.GetEnv("MONGO_DATABASE", "localhost:27017"))
+	db := mongoClient.Database(cfg.Mongo.Database)
 
 	// Initialize Redis
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     envConfig.GetEnv("REDIS_ADDR", "localhost:6379"),
-		Password: envConfig.GetEnv("REDIS_PASSWORD", ""),
-		DB:       0,
-	})
-	defer redisClient.Close()
-
-	// Test Redis connection
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+	redisClient, err := redisConfig.ConnectRedis(&cfg.Redis)
+	if err != nil {
 		logger.Fatal("Failed to connect to Redis", logger.WithError(err))
 	}
+	defer redisClient.Close()
 
 	// Initialize Pattern Classifier
-	patternClassifier := core.NewPatternClassifier()
+	patternClassifier := core.NewPatternClassifier(core.NewLLMClient(cfg.LLM.APIKey, cfg.LLM.Model, cfg.LLM.Timeout))
 	if err := initializePatternClassifier(patternClassifier, db); err != nil {
 		logger.Fatal("Failed to initialize pattern classifier", logger.WithError(err))
 	}
@@ -72,9 +85,35 @@ func main() {
 	categorizedDataRepo := mongoAdapter.NewCategorizedDataRepositoryPort(db)
 	pomodoroUsageLogRepo := mongoAdapter.NewPomodoroUsageLogRepositoryPort(db)
 	categoryPatternRepo := mongoAdapter.NewCategoryPatternRepositoryPort(db)
+	deferredClassificationRepo := mongoAdapter.NewDeferredClassificationRepositoryPort(db)
+	sessionScoreOutboxRepo := mongoAdapter.NewSessionScoreOutboxRepositoryPort(db)
+	usageCreditRepo := mongoAdapter.NewUsageCreditRepositoryPort(db)
+	transaction := mongoAdapter.NewTransactionPort(mongoClient, cfg.Mongo.Transactions)
+	if err := mongoAdapter.EnsureSessionScoreOutboxIndexes(context.Background(), db); err != nil {
+		logger.Fatal("Failed to create session score outbox indexes", logger.WithError(err))
+	}
+	if err := mongoAdapter.EnsureUsageCreditIndexes(context.Background(), db); err != nil {
+		logger.Fatal("Failed to create usage credit indexes", logger.WithError(err))
+	}
+	if err := mongoAdapter.EnsureDeferredClassificationIndexes(context.Background(), db); err != nil {
+		logger.Fatal("Failed to create deferred classification indexes", logger.WithError(err))
+	}
 
 	// Create Redis adapters
-	leaderboardCache := redisAdapter.NewLeaderboardCachePort(redisClient)
+	// Group boards are updated alongside the global ones, from memberships cached in-process
+	groupMemberships := leaderboardService.NewGroupMembershipCache(mongoAdapter.NewGroupMembershipRepositoryPort(db))
+	if err := groupMemberships.Refresh(context.Background()); err != nil {
+		logger.Warn("Failed to load group memberships", logger.WithError(err))
+	}
+	leaderboardCache := leaderboardService.NewGroupLeaderboardService(
+		redisAdapter.NewLeaderboardCachePort(
+			redisClient,
+			leaderboardKeyLayout,
+			cfg.Leaderboard.DedupeTTL,
+		),
+		groupMemberships,
+		leaderboardKeyLayout,
+	)
 	classifierAdapter := redisAdapter.NewPatternClassifierAdapter(patternClassifier)
 
 	// Create services
@@ -85,43 +124,168 @@ func main() {
 		classifierAdapter,
 		categorizedDataRepo,
 		pomodoroUsageLogRepo,
+		deferredClassificationRepo,
+		sessionScoreOutboxRepo,
+		usageCreditRepo,
+		transaction,
+		categoryPatternUseCase,
+		leaderboardCache,
+		bucketing,
+		cfg.Classifier.Workers,
+		cfg.Classifier.Timeout,
+	)
+
+	retryDeferredUseCase := pomodoroService.NewDeferredClassificationService(
+		classifierAdapter,
+		deferredClassificationRepo,
+		categorizedDataRepo,
+		pomodoroUsageLogRepo,
 		categoryPatternUseCase,
 		leaderboardCache,
+		bucketing,
+	)
+
+	sessionCompletionUseCase := pomodoroService.NewSessionCompletionService(
+		pomodoroUsageLogRepo,
+		deferredClassificationRepo,
+		sessionScoreOutboxRepo,
+		redisAdapter.NewSessionScoreEventPort(redisClient, cfg.Streams.SessionScoreStreamKey),
+		cfg.Sessions.CompletionTimeout,
+	)
+
+	deadLetters := consumer.NewDeadLetterQueue(
+		redisClient,
+		cfg.Streams.DeadLetter.StreamKey,
+		cfg.Streams.DeadLetter.MaxLen,
 	)
 
 	// Create message processor adapter
 	messageProcessor := redisAdapter.NewPomodoroMessageProcessorAdapter(
 		classifyUseCase,
-		redisClient,
+		sessionCompletionUseCase,
+		deadLetters,
 	)
 
-	// Configure stream
-	streamConfig := consumer.StreamConfig{
-		StreamKey: redisConfig.PomodoroPatternMatch.StreamKey,
-		Group:     redisConfig.PomodoroPatternMatch.Group,
-		Consumer:  redisConfig.PomodoroPatternMatch.Consumer,
+	// Register processors and start every configured stream consumer
+	consumerRegistry := consumer.NewRegistry(redisClient, deadLetters)
+	consumerRegistry.RegisterProcessor(redisConfig.PatternMatchPipeline, messageProcessor)
+	if err := consumerRegistry.Build(cfg.Streams.EnabledConsumers()); err != nil {
+		logger.Fatal("Failed to build stream consumers", logger.WithError(err))
 	}
 
-	// Create abstract consumer with the processor
-	pomodoroConsumer := consumer.NewAbstractConsumer(
-		redisClient,
-		streamConfig,
-		messageProcessor,
-		10,            // workerPool
-		50,            // batchSize
-		2*time.Second, // blockTime
+	if err := consumerRegistry.StartAll(); err != nil {
+		logger.Fatal("Failed to start stream consumers", logger.WithError(err))
+	}
+
+	consumerStatsTask := scheduler.NewPeriodicTask(
+		"consumer_stats",
+		cfg.Intervals.ConsumerStats,
+		func(ctx context.Context) error {
+			consumerRegistry.LogStats()
+			return nil
+		},
 	)
+	consumerStatsTask.Start()
 
-	if err := pomodoroConsumer.Start(); err != nil {
-		logger.Fatal("Failed to start pomodoro consumer", logger.WithError(err))
+	// Publish stream backlog metrics and alert when a group falls behind
+	var streamAlertNotifier consumer.StreamAlertNotifier
+	if cfg.Monitor.WebhookURL != "" {
+		streamAlertNotifier = webhook.NewStreamAlertWebhook(cfg.Monitor.WebhookURL, cfg.Monitor.WebhookTimeout)
 	}
+	streamMonitor := consumer.NewStreamMonitor(
+		redisClient,
+		consumerRegistry,
+		consumer.StreamMonitorThresholds{
+			Lag:              cfg.Monitor.LagThreshold,
+			Pending:          cfg.Monitor.PendingThreshold,
+			OldestPendingAge: cfg.Monitor.OldestPendingAgeThreshold,
+		},
+		streamAlertNotifier,
+	)
+	streamMonitorTask := scheduler.NewPeriodicTask(
+		"stream_monitor",
+		cfg.Intervals.StreamMonitor,
+		streamMonitor.Check,
+	)
+	streamMonitorTask.Start()
+
+	// Retry classifications that failed on the first pass
+	deferredRetryTask := scheduler.NewPeriodicTask(
+		"deferred_classification_retry",
+		cfg.Intervals.DeferredRetry,
+		func(ctx context.Context) error {
+			_, err := retryDeferredUseCase.RetryDue(ctx)
+			return err
+		},
+	)
+	deferredRetryTask.Start()
+
+	// Relay session score events from the outbox once their sessions are fully categorized
+	sessionOutboxRelayTask := scheduler.NewPeriodicTask(
+		"session_score_outbox_relay",
+		cfg.Intervals.SessionOutboxRelay,
+		func(ctx context.Context) error {
+			_, err := sessionCompletionUseCase.RelayPending(ctx)
+			return err
+		},
+	)
+	sessionOutboxRelayTask.Start()
+
+	groupMembershipRefreshTask := scheduler.NewPeriodicTask(
+		"group_membership_refresh",
+		cfg.Intervals.GroupMembershipRefresh,
+		groupMemberships.Refresh,
+	)
+	groupMembershipRefreshTask.Start()
+
+	mux := http.NewServeMux()
+	handler.NewGroupLeaderboardHandler(leaderboardCache, bucketing).Register(mux)
+	mux.Handle("GET /metrics", metrics.Handler())
+	handler.NewHealthHandler(
+		[]handler.HealthCheck{
+			{Name: "consume_loop", Check: func(ctx context.Context) error {
+				return consumerRegistry.CheckReadLoops(cfg.Health.ConsumeTimeout)
+			}},
+		},
+		[]handler.HealthCheck{
+			{Name: "mongo", Check: func(ctx context.Context) error {
+				return mongoClient.Ping(ctx, readpref.Primary())
+			}},
+			{Name: "redis", Check: func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			}},
+			{Name: "patterns", Check: func(ctx context.Context) error {
+				if patternClassifier.PatternCount() == 0 {
+					return errors.New("no category patterns loaded")
+				}
+				return nil
+			}},
+			{Name: "consumer_groups", Check: consumerRegistry.CheckGroups},
+		},
+	).Register(mux)
+	httpServer := server.NewServer(cfg.HTTP.Addr, mux)
+	httpServer.Start()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
 	logger.Info("Shutting down...")
-	pomodoroConsumer.Stop()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	httpServer.Stop(shutdownCtx)
+	cancelShutdown()
+	groupMembershipRefreshTask.Stop()
+	deferredRetryTask.Stop()
+	streamMonitorTask.Stop()
+	consumerStatsTask.Stop()
+	consumerRegistry.StopAll()
+	sessionOutboxRelayTask.Stop()
+
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.Warn("Failed to flush traces", logger.WithError(err))
+	}
+	cancelTracing()
 	logger.Info("Shutdown complete")
 }
 
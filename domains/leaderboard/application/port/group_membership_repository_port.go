@@ -0,0 +1,10 @@
+package port
+
+import (
+	"context"
+)
+
+type GroupMembershipRepositoryPort interface {
+	// FindAllGroupIDsByUser returns every user's group IDs
+	FindAllGroupIDsByUser(ctx context.Context) (map[string][]string, error)
+}
@@ -9,4 +9,10 @@ type LeaderboardCachePort interface {
 
 	// BatchIncreaseScore increases multiple users' scores in batch
 	BatchIncreaseScore(ctx context.Context, entries []*domain.LeaderboardEntry) error
+
+	// ReplaceScores atomically swaps a board's contents for the given member scores, keeping the period's retention
+	ReplaceScores(ctx context.Context, key string, period domain.Period, scores map[string]float64) error
+
+	// GetTopRanking returns the highest scored members of a board, best first
+	GetTopRanking(ctx context.Context, key string, limit int64) ([]*domain.LeaderboardResult, error)
 }
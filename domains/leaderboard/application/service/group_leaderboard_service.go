@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"pomocore-data/domains/leaderboard/application/port"
+	"pomocore-data/domains/leaderboard/domain"
+)
+
+// GroupLeaderboardService adds group boards to every score update and reads group rankings
+type GroupLeaderboardService struct {
+	leaderboardCache port.LeaderboardCachePort
+	memberships      *GroupMembershipCache
+	keyLayout        domain.KeyLayout
+}
+
+func NewGroupLeaderboardService(
+	leaderboardCache port.LeaderboardCachePort,
+	memberships *GroupMembershipCache,
+	keyLayout domain.KeyLayout,
+) *GroupLeaderboardService {
+	return &GroupLeaderboardService{
+		leaderboardCache: leaderboardCache,
+		memberships:      memberships,
+		keyLayout:        keyLayout,
+	}
+}
+
+// BatchIncreaseScore attaches each user's current groups before updating the boards
+func (s *GroupLeaderboardService) BatchIncreaseScore(ctx context.Context, entries []*domain.LeaderboardEntry) error {
+	for _, entry := range entries {
+		entry.GroupIDs = s.memberships.GroupIDs(entry.UserID)
+	}
+	return s.leaderboardCache.BatchIncreaseScore(ctx, entries)
+}
+
+func (s *GroupLeaderboardService) ReplaceScores(ctx context.Context, key string, period domain.Period, scores map[string]float64) error {
+	return s.leaderboardCache.ReplaceScores(ctx, key, period, scores)
+}
+
+func (s *GroupLeaderboardService) GetTopRanking(ctx context.Context, key string, limit int64) ([]*domain.LeaderboardResult, error) {
+	return s.leaderboardCache.GetTopRanking(ctx, key, limit)
+}
+
+func (s *GroupLeaderboardService) GetGroupRanking(
+	ctx context.Context,
+	groupID, category string,
+	period domain.Period,
+	day time.Time,
+	limit int64,
+) ([]*domain.LeaderboardResult, error) {
+	key := domain.GroupLeaderboardKey(s.keyLayout, groupID, category, period, day)
+	return s.leaderboardCache.GetTopRanking(ctx, key, limit)
+}
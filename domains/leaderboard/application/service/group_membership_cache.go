@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/domains/leaderboard/application/port"
+	"pomocore-data/shared/common/logger"
+)
+
+// GroupMembershipCache keeps every user's group IDs in memory; Refresh reloads them from the repository
+type GroupMembershipCache struct {
+	repo           port.GroupMembershipRepositoryPort
+	groupIDsByUser map[string][]string
+	mu             sync.RWMutex
+}
+
+func NewGroupMembershipCache(repo port.GroupMembershipRepositoryPort) *GroupMembershipCache {
+	return &GroupMembershipCache{
+		repo:           repo,
+		groupIDsByUser: make(map[string][]string),
+	}
+}
+
+func (c *GroupMembershipCache) Refresh(ctx context.Context) error {
+	groupIDsByUser, err := c.repo.FindAllGroupIDsByUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.groupIDsByUser = groupIDsByUser
+	c.mu.Unlock()
+
+	logger.Debug("Refreshed group memberships", zap.Int("user_count", len(groupIDsByUser)))
+	return nil
+}
+
+func (c *GroupMembershipCache) GroupIDs(userID string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.groupIDsByUser[userID]
+}
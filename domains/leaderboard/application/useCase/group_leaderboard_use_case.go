@@ -0,0 +1,13 @@
+package useCase
+
+import (
+	"context"
+	"time"
+
+	"pomocore-data/domains/leaderboard/domain"
+)
+
+type GroupLeaderboardUseCase interface {
+	// GetGroupRanking returns the top members of a group's board for the period containing day
+	GetGroupRanking(ctx context.Context, groupID, category string, period domain.Period, day time.Time, limit int64) ([]*domain.LeaderboardResult, error)
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DaySource selects what a leaderboard entry's day is derived from
+type DaySource string
+
+const (
+	// DaySourceSessionDate attributes usage to the day of its session, the same way the app displays it
+	DaySourceSessionDate DaySource = "session_date"
+	DaySourceTimestamp   DaySource = "timestamp"
+)
+
+func ParseDaySource(s string) (DaySource, error) {
+	switch source := DaySource(s); source {
+	case DaySourceSessionDate, DaySourceTimestamp:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unknown leaderboard day source %q", s)
+	}
+}
+
+// Bucketing decides which daily, weekly and monthly boards an entry belongs to
+type Bucketing struct {
+	location  *time.Location
+	daySource DaySource
+	locations sync.Map
+}
+
+func NewBucketing(location *time.Location, daySource DaySource) *Bucketing {
+	return &Bucketing{
+		location:  location,
+		daySource: daySource,
+	}
+}
+
+// Day returns the leaderboard day in the user's timezone, or the configured one when timezone is empty.
+// A sessionDate at midnight is taken as a calendar date; one with a time of day is converted into the timezone first.
+// The day is returned as UTC midnight of that calendar date so it survives being stored and reloaded.
+func (b *Bucketing) Day(sessionDate time.Time, timestamp float64, timezone string) time.Time {
+	loc := b.Location(timezone)
+
+	var year, day int
+	var month time.Month
+	switch {
+	case b.daySource == DaySourceSessionDate && !sessionDate.IsZero():
+		if sessionDate.Hour() != 0 || sessionDate.Minute() != 0 || sessionDate.Second() != 0 {
+			sessionDate = sessionDate.In(loc)
+		}
+		year, month, day = sessionDate.Date()
+	default:
+		year, month, day = time.Unix(int64(timestamp), 0).In(loc).Date()
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Location resolves an IANA timezone name, falling back to the configured timezone for empty or unknown names.
+// Only valid names are cached so arbitrary client input cannot grow the cache.
+func (b *Bucketing) Location(timezone string) *time.Location {
+	if timezone == "" {
+		return b.location
+	}
+	if loc, ok := b.locations.Load(timezone); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return b.location
+	}
+	b.locations.Store(timezone, loc)
+	return loc
+}
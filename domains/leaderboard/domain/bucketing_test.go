@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketingDay(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	unix := func(s string) float64 {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", s, err)
+		}
+		return float64(ts.Unix())
+	}
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		daySource   DaySource
+		sessionDate time.Time
+		timestamp   float64
+		timezone    string
+		want        time.Time
+	}{
+		{
+			name:        "calendar session date ignores the timezone",
+			daySource:   DaySourceSessionDate,
+			sessionDate: date(2025, 3, 1),
+			timestamp:   unix("2025-03-02T10:00:00Z"),
+			timezone:    "America/Los_Angeles",
+			want:        date(2025, 3, 1),
+		},
+		{
+			name:        "session date with a time of day crosses midnight in the user's timezone",
+			daySource:   DaySourceSessionDate,
+			sessionDate: time.Date(2025, 3, 1, 15, 30, 0, 0, time.UTC),
+			timezone:    "Asia/Seoul",
+			want:        date(2025, 3, 2),
+		},
+		{
+			name:        "session date with a time of day west of UTC",
+			daySource:   DaySourceSessionDate,
+			sessionDate: time.Date(2025, 3, 1, 5, 0, 0, 0, time.UTC),
+			timezone:    "America/Los_Angeles",
+			want:        date(2025, 2, 28),
+		},
+		{
+			name:      "missing session date falls back to the timestamp",
+			daySource: DaySourceSessionDate,
+			timestamp: unix("2025-02-28T23:30:00Z"),
+			timezone:  "Asia/Seoul",
+			want:      date(2025, 3, 1),
+		},
+		{
+			name:        "timestamp source ignores the session date",
+			daySource:   DaySourceTimestamp,
+			sessionDate: date(2025, 2, 27),
+			timestamp:   unix("2025-02-28T23:30:00Z"),
+			timezone:    "UTC",
+			want:        date(2025, 2, 28),
+		},
+		{
+			name:      "year boundary",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2024-12-31T20:00:00Z"),
+			timezone:  "Asia/Seoul",
+			want:      date(2025, 1, 1),
+		},
+		{
+			name:      "leap day",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2024-02-29T14:59:59Z"),
+			timezone:  "Asia/Seoul",
+			want:      date(2024, 2, 29),
+		},
+		{
+			name:      "day after leap day",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2024-02-29T15:00:00Z"),
+			timezone:  "Asia/Seoul",
+			want:      date(2024, 3, 1),
+		},
+		{
+			name:      "before the daylight saving change",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2025-03-09T04:30:00Z"),
+			timezone:  "America/New_York",
+			want:      date(2025, 3, 8),
+		},
+		{
+			name:      "after the daylight saving change",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2025-03-10T04:30:00Z"),
+			timezone:  "America/New_York",
+			want:      date(2025, 3, 10),
+		},
+		{
+			name:      "empty timezone uses the configured one",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2025-02-28T15:00:00Z"),
+			want:      date(2025, 3, 1),
+		},
+		{
+			name:      "unknown timezone uses the configured one",
+			daySource: DaySourceTimestamp,
+			timestamp: unix("2025-02-28T15:00:00Z"),
+			timezone:  "Mars/Olympus",
+			want:      date(2025, 3, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucketing := NewBucketing(seoul, tt.daySource)
+			got := bucketing.Day(tt.sessionDate, tt.timestamp, tt.timezone)
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("Day = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDaySource(t *testing.T) {
+	for _, s := range []string{"session_date", "timestamp"} {
+		if source, err := ParseDaySource(s); err != nil || string(source) != s {
+			t.Errorf("ParseDaySource(%q) = %q, %v", s, source, err)
+		}
+	}
+	if _, err := ParseDaySource("created_at"); err == nil {
+		t.Error("ParseDaySource(created_at) succeeded")
+	}
+}
+
+func TestBucketingLocationCachesOnlyValidNames(t *testing.T) {
+	b := NewBucketing(time.UTC, DaySourceSessionDate)
+
+	if loc := b.Location("Asia/Seoul"); loc.String() != "Asia/Seoul" {
+		t.Errorf("Location(Asia/Seoul) = %s", loc)
+	}
+	if _, ok := b.locations.Load("Asia/Seoul"); !ok {
+		t.Error("Asia/Seoul was not cached")
+	}
+
+	if loc := b.Location("Not/AZone"); loc != time.UTC {
+		t.Errorf("Location(Not/AZone) = %s, want the configured UTC", loc)
+	}
+	if _, ok := b.locations.Load("Not/AZone"); ok {
+		t.Error("Not/AZone was cached")
+	}
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// KeyScheme is the format of weekly and monthly leaderboard periods
+type KeyScheme int
+
+const (
+	// KeySchemeV1 is the original unpadded format: 2025-W5, 2025-M3
+	KeySchemeV1 KeyScheme = 1
+	// KeySchemeV2 is zero-padded and sortable: 2025-W05, 2025-03
+	KeySchemeV2 KeyScheme = 2
+)
+
+func (k KeyScheme) String() string {
+	return "v" + strconv.Itoa(int(k))
+}
+
+func (k KeyScheme) weekPeriod(year, week int) string {
+	if k == KeySchemeV1 {
+		return fmt.Sprintf("%d-W%d", year, week)
+	}
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+func (k KeyScheme) monthPeriod(year, month int) string {
+	if k == KeySchemeV1 {
+		return fmt.Sprintf("%d-M%d", year, month)
+	}
+	return fmt.Sprintf("%d-%02d", year, month)
+}
+
+// ParseKeySchemes parses a comma separated list such as "v1,v2"; listing both schemes dual-writes during a migration
+func ParseKeySchemes(s string) ([]KeyScheme, error) {
+	var schemes []KeyScheme
+	seen := make(map[KeyScheme]bool)
+	for _, name := range strings.Split(s, ",") {
+		var scheme KeyScheme
+		switch strings.TrimSpace(name) {
+		case "v1":
+			scheme = KeySchemeV1
+		case "v2":
+			scheme = KeySchemeV2
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown leaderboard key scheme %q", name)
+		}
+		if !seen[scheme] {
+			seen[scheme] = true
+			schemes = append(schemes, scheme)
+		}
+	}
+	if len(schemes) == 0 {
+		return nil, fmt.Errorf("at least one leaderboard key scheme is required")
+	}
+	return schemes, nil
+}
+
+// KeyLayout describes how leaderboard keys are built
+type KeyLayout struct {
+	// Schemes lists every key scheme written to; more than one while readers migrate
+	Schemes []KeyScheme
+	// HashTag, when set, is added to the key prefix in {} so every leaderboard key lands in one Redis Cluster
+	// slot, which the multi-key update script requires. That slot's node then serves all leaderboard traffic.
+	HashTag string
+}
+
+// Prefix starts every leaderboard key: leaderboard, or leaderboard:{<hashTag>} in cluster mode, so readers
+// only gain a segment and keys stay under the leaderboard: namespace
+func (l KeyLayout) Prefix() string {
+	if l.HashTag != "" {
+		return "leaderboard:{" + l.HashTag + "}"
+	}
+	return "leaderboard"
+}
+
+// ReadScheme is the newest written scheme, used when reading boards
+func (l KeyLayout) ReadScheme() KeyScheme {
+	return slices.Max(l.Schemes)
+}
+
+var (
+	v1WeekPeriod  = regexp.MustCompile(`^(\d{4})-W(\d{1,2})$`)
+	v1MonthPeriod = regexp.MustCompile(`^(\d{4})-M(\d{1,2})$`)
+)
+
+// UpgradeLeaderboardKey returns the v2 key for a v1 weekly or monthly key, and false for any other key
+func UpgradeLeaderboardKey(key string) (string, bool) {
+	sep := strings.LastIndex(key, ":")
+	if sep < 0 {
+		return "", false
+	}
+	prefix, period := key[:sep+1], key[sep+1:]
+
+	if m := v1WeekPeriod.FindStringSubmatch(period); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+		upgraded := prefix + KeySchemeV2.weekPeriod(year, week)
+		return upgraded, upgraded != key
+	}
+	if m := v1MonthPeriod.FindStringSubmatch(period); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		return prefix + KeySchemeV2.monthPeriod(year, month), true
+	}
+	return "", false
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUpgradeLeaderboardKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"leaderboard:work:2025-W5", "leaderboard:work:2025-W05", true},
+		{"leaderboard:work:2025-W52", "leaderboard:work:2025-W52", false},
+		{"leaderboard:work:2025-W05", "leaderboard:work:2025-W05", false},
+		{"leaderboard:Development:2025-M3", "leaderboard:Development:2025-03", true},
+		{"leaderboard:Development:2025-M12", "leaderboard:Development:2025-12", true},
+		{"leaderboard:{lb}:group:g1:work:2025-M3", "leaderboard:{lb}:group:g1:work:2025-03", true},
+		{"leaderboard:work:2025-03", "", false},
+		{"leaderboard:work:2025-03-01", "", false},
+		{"leaderboard:work:2025", "", false},
+		{"leaderboard:work:all", "", false},
+		{"leaderboard:work:2025-W123", "", false},
+		{"2025-W5", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := UpgradeLeaderboardKey(tt.key)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("UpgradeLeaderboardKey(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// Every v1 key written for a day upgrades to the v2 key written for the same day
+func TestUpgradeLeaderboardKeyMatchesV2Keys(t *testing.T) {
+	v1 := KeyLayout{Schemes: []KeyScheme{KeySchemeV1}, HashTag: "lb"}
+	v2 := KeyLayout{Schemes: []KeyScheme{KeySchemeV2}, HashTag: "lb"}
+	periods := []Period{PeriodWeekly, PeriodMonthly}
+
+	for _, day := range []time.Time{
+		time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), // ISO week 1 of 2025
+		time.Date(2025, 10, 6, 0, 0, 0, 0, time.UTC),
+	} {
+		v1Keys := leaderboardKeys(v1, "work", day, periods)
+		v2Keys := leaderboardKeys(v2, "work", day, periods)
+		for i, key := range v1Keys {
+			if got, _ := UpgradeLeaderboardKey(key); got != v2Keys[i] {
+				t.Errorf("%s: UpgradeLeaderboardKey(%q) = %q, want %q", day.Format("2006-01-02"), key, got, v2Keys[i])
+			}
+		}
+	}
+}
+
+func TestParseKeySchemes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []KeyScheme
+		wantErr bool
+	}{
+		{in: "v1", want: []KeyScheme{KeySchemeV1}},
+		{in: "v2", want: []KeyScheme{KeySchemeV2}},
+		{in: "v1,v2", want: []KeyScheme{KeySchemeV1, KeySchemeV2}},
+		{in: " v2 , v1 ", want: []KeyScheme{KeySchemeV2, KeySchemeV1}},
+		{in: "v2,v2,", want: []KeyScheme{KeySchemeV2}},
+		{in: "", wantErr: true},
+		{in: ",", wantErr: true},
+		{in: "v3", wantErr: true},
+		{in: "v1,V2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKeySchemes(tt.in)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseKeySchemes(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
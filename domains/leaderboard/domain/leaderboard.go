@@ -2,6 +2,9 @@ package domain
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,73 +17,191 @@ type LeaderboardResult struct {
 
 // LeaderboardEntry represents a single entry for leaderboard operations
 type LeaderboardEntry struct {
+	// EntryID identifies the entry so a redelivered entry is applied once; empty disables deduplication
+	EntryID   string
 	UserID    string
 	Category  string
 	Duration  float64
 	Timestamp float64
+	// Day is the leaderboard day resolved by Bucketing
+	Day time.Time
+	// GroupIDs are the user's groups whose boards the entry is also added to
+	GroupIDs []string
 }
 
-func NewLeaderboardEntry(userID, category string, duration, timestamp float64) *LeaderboardEntry {
+func NewLeaderboardEntry(entryID, userID, category string, duration, timestamp float64, day time.Time) *LeaderboardEntry {
 	return &LeaderboardEntry{
+		EntryID:   entryID,
 		UserID:    userID,
 		Category:  category,
 		Duration:  duration,
 		Timestamp: timestamp,
+		Day:       day,
 	}
 }
 
-var keyFormat = "leaderboard:%s:%s"
+var keyFormat = "%s:%s:%s"
 
-func getDailyLeaderboardKey(category string, day time.Time) string {
+// groupBoard scopes a category board to a group: leaderboard:group:<groupId>:<category>:<period>
+func groupBoard(groupID, category string) string {
+	return "group:" + groupID + ":" + category
+}
+
+// Period is the time span a leaderboard accumulates over
+type Period string
+
+const (
+	PeriodDaily   Period = "daily"
+	PeriodWeekly  Period = "weekly"
+	PeriodMonthly Period = "monthly"
+	PeriodYearly  Period = "yearly"
+	PeriodAllTime Period = "all_time"
+)
+
+// AllPeriods lists every board an entry is added to
+var AllPeriods = []Period{PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodYearly, PeriodAllTime}
+
+func ParsePeriod(s string) (Period, error) {
+	period := Period(s)
+	if !slices.Contains(AllPeriods, period) {
+		return "", fmt.Errorf("unknown leaderboard period %q", s)
+	}
+	return period, nil
+}
+
+func ParsePeriods(s string) ([]Period, error) {
+	var periods []Period
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		period, err := ParsePeriod(name)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("at least one leaderboard period is required")
+	}
+	return periods, nil
+}
+
+// dependsOnScheme reports whether the period is formatted differently across key schemes
+func (p Period) dependsOnScheme() bool {
+	return p == PeriodWeekly || p == PeriodMonthly
+}
+
+func getDailyLeaderboardKey(prefix, category string, day time.Time) string {
 	dateStr := day.Format("2006-01-02")
-	return fmt.Sprintf(keyFormat, category, dateStr)
+	return fmt.Sprintf(keyFormat, prefix, category, dateStr)
 }
 
-func getWeeklyLeaderboardKey(category string, day time.Time) string {
+func getWeeklyLeaderboardKey(prefix, category string, day time.Time, scheme KeyScheme) string {
 	year, week := day.ISOWeek()
-	weekStr := fmt.Sprintf("%d-W%d", year, week)
-	return fmt.Sprintf(keyFormat, category, weekStr)
+	return fmt.Sprintf(keyFormat, prefix, category, scheme.weekPeriod(year, week))
 }
 
-func getMonthlyLeaderboardKey(category string, day time.Time) string {
-	year := day.Year()
-	month := int(day.Month())
-	monthStr := fmt.Sprintf("%d-M%d", year, month)
-	return fmt.Sprintf(keyFormat, category, monthStr)
+func getMonthlyLeaderboardKey(prefix, category string, day time.Time, scheme KeyScheme) string {
+	return fmt.Sprintf(keyFormat, prefix, category, scheme.monthPeriod(day.Year(), int(day.Month())))
 }
 
-func (e *LeaderboardEntry) GetWorkLeaderboardKeys() []string {
-	res := make([]string, 3)
-	day := time.Unix(int64(e.Timestamp), 0)
-	res[0] = getDailyLeaderboardKey("work", day)
-	res[1] = getWeeklyLeaderboardKey("work", day)
-	res[2] = getMonthlyLeaderboardKey("work", day)
-	return res
+func getYearlyLeaderboardKey(prefix, category string, day time.Time) string {
+	return fmt.Sprintf(keyFormat, prefix, category, strconv.Itoa(day.Year()))
+}
+
+func getAllTimeLeaderboardKey(prefix, category string) string {
+	return fmt.Sprintf(keyFormat, prefix, category, "all")
+}
+
+func getLeaderboardKey(prefix, category string, day time.Time, period Period, scheme KeyScheme) string {
+	switch period {
+	case PeriodWeekly:
+		return getWeeklyLeaderboardKey(prefix, category, day, scheme)
+	case PeriodMonthly:
+		return getMonthlyLeaderboardKey(prefix, category, day, scheme)
+	case PeriodYearly:
+		return getYearlyLeaderboardKey(prefix, category, day)
+	case PeriodAllTime:
+		return getAllTimeLeaderboardKey(prefix, category)
+	default:
+		return getDailyLeaderboardKey(prefix, category, day)
+	}
 }
 
-func (e *LeaderboardEntry) GetCategoryLeaderboardKeys() []string {
-	res := make([]string, 3)
-	day := time.Unix(int64(e.Timestamp), 0)
-	res[0] = getDailyLeaderboardKey(e.Category, day)
-	res[1] = getWeeklyLeaderboardKey(e.Category, day)
-	res[2] = getMonthlyLeaderboardKey(e.Category, day)
+// leaderboardKeys returns the keys of the given periods in every written scheme; periods formatted the same way in all schemes appear once
+func leaderboardKeys(layout KeyLayout, category string, day time.Time, periods []Period) []string {
+	prefix := layout.Prefix()
+	res := make([]string, 0, len(periods)*len(layout.Schemes))
+	for _, period := range periods {
+		if !period.dependsOnScheme() {
+			res = append(res, getLeaderboardKey(prefix, category, day, period, KeySchemeV2))
+			continue
+		}
+		for _, scheme := range layout.Schemes {
+			res = append(res, getLeaderboardKey(prefix, category, day, period, scheme))
+		}
+	}
 	return res
 }
 
+func (e *LeaderboardEntry) GetWorkLeaderboardKeys(layout KeyLayout, periods []Period) []string {
+	return leaderboardKeys(layout, "work", e.Day, periods)
+}
+
+func (e *LeaderboardEntry) GetCategoryLeaderboardKeys(layout KeyLayout, periods []Period) []string {
+	return leaderboardKeys(layout, e.Category, e.Day, periods)
+}
+
+// GetGroupLeaderboardKeys returns the category keys, and the work keys when the category counts as work, of each of the entry's groups
+func (e *LeaderboardEntry) GetGroupLeaderboardKeys(layout KeyLayout, periods []Period) []string {
+	var keys []string
+	for _, groupID := range e.GroupIDs {
+		keys = append(keys, leaderboardKeys(layout, groupBoard(groupID, e.Category), e.Day, periods)...)
+		if e.IsWorkCategory() {
+			keys = append(keys, leaderboardKeys(layout, groupBoard(groupID, "work"), e.Day, periods)...)
+		}
+	}
+	return keys
+}
+
+// GetLeaderboardKeys returns the global category keys plus the work keys when the category counts as work,
+// followed by the group keys
+func (e *LeaderboardEntry) GetLeaderboardKeys(layout KeyLayout, periods []Period) []string {
+	keys := e.GetCategoryLeaderboardKeys(layout, periods)
+	if e.IsWorkCategory() {
+		keys = append(keys, e.GetWorkLeaderboardKeys(layout, periods)...)
+	}
+	return append(keys, e.GetGroupLeaderboardKeys(layout, periods)...)
+}
+
+// GroupLeaderboardKey returns the key of one group board in the layout's read scheme
+func GroupLeaderboardKey(layout KeyLayout, groupID, category string, period Period, day time.Time) string {
+	return getLeaderboardKey(layout.Prefix(), groupBoard(groupID, category), day, period, layout.ReadScheme())
+}
+
 func (e *LeaderboardEntry) IsWorkCategory() bool {
-	workCategory := make(map[string]bool)
-	workCategory["Development"] = true
-	workCategory["LLM"] = true
-	workCategory["Documentation"] = true
-	workCategory["Design"] = true
-	workCategory["Video Editing"] = true
-	workCategory["Education"] = true
-	workCategory["Productivity"] = true
-	workCategory["Finance"] = true
-	workCategory["File Management"] = true
-	workCategory["Browsing"] = true
-	workCategory["Marketing"] = true
-	workCategory["System & Utilities"] = true
-	workCategory["Meetings"] = true
-	return workCategory[e.Category] == true
+	return IsWorkCategory(e.Category)
+}
+
+var workCategories = map[string]bool{
+	"Development":        true,
+	"LLM":                true,
+	"Documentation":      true,
+	"Design":             true,
+	"Video Editing":      true,
+	"Education":          true,
+	"Productivity":       true,
+	"Finance":            true,
+	"File Management":    true,
+	"Browsing":           true,
+	"Marketing":          true,
+	"System & Utilities": true,
+	"Meetings":           true,
+}
+
+// IsWorkCategory reports whether time spent in the category counts towards the work leaderboards
+func IsWorkCategory(category string) bool {
+	return workCategories[category]
 }
@@ -1,8 +1,21 @@
 package message
 
 import (
+	"fmt"
 	"strconv"
+	"sync"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxUsageDuration bounds a single usage log; no pomodoro session runs longer than this
+	MaxUsageDuration = 4 * 60 * 60.0
+
+	// Timestamps before this or too far in the future are treated as client clock errors
+	minTimestamp          = 1577836800 // 2020-01-01T00:00:00Z
+	maxTimestampClockSkew = 24 * time.Hour
 )
 
 type PomodoroPatternClassifyMessage struct {
@@ -18,10 +31,32 @@ type PomodoroPatternClassifyMessage struct {
 	Duration           float64   `json:"duration"`
 	Timestamp          float64   `json:"timestamp"`
 	IsEnd              bool      `json:"isEnd"`
+	Timezone           string    `json:"timezone,omitempty"` // IANA name; empty means the configured leaderboard timezone
 }
 
-// ParseFromRedisValues creates a message from Redis stream values (all strings)
+// ParseFromRedisValues creates a message from Redis stream values, either flattened string fields
+// or a single versioned payload field. Any unparsable or invalid field is reported through a *ValidationError.
 func ParseFromRedisValues(values map[string]interface{}) (*PomodoroPatternClassifyMessage, error) {
+	validationErr := &ValidationError{}
+
+	var msg *PomodoroPatternClassifyMessage
+	if _, ok := values[PayloadField]; ok {
+		if msg = parsePayload(values, validationErr); msg == nil {
+			return &PomodoroPatternClassifyMessage{}, validationErr
+		}
+	} else {
+		msg = parseFlatValues(values, validationErr)
+	}
+
+	msg.validate(validationErr, time.Now())
+	if err := validationErr.orNil(); err != nil {
+		return msg, err
+	}
+	return msg, nil
+}
+
+// parseFlatValues reads the legacy format where every field is a separate string value
+func parseFlatValues(values map[string]interface{}, validationErr *ValidationError) *PomodoroPatternClassifyMessage {
 	msg := &PomodoroPatternClassifyMessage{}
 
 	// Helper function to get string value
@@ -40,27 +75,31 @@ func ParseFromRedisValues(values map[string]interface{}) (*PomodoroPatternClassi
 	msg.URL = getString("url")
 	msg.Title = getString("title")
 	msg.App = getString("app")
+	msg.Timezone = getString("timezone")
 
 	// Parse integer fields
 	if s := getString("session"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil {
 			msg.Session = v
+		} else {
+			validationErr.add("session", ErrCodeInvalidFormat, s)
 		}
 	}
 
 	if s := getString("sessionMinutes"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil {
 			msg.SessionMinutes = v
+		} else {
+			validationErr.add("sessionMinutes", ErrCodeInvalidFormat, s)
 		}
 	}
 
 	// Parse date field
 	if s := getString("sessionDate"); s != "" {
-		// Try date-only format first
-		if t, err := time.Parse("2006-01-02", s); err == nil {
-			msg.SessionDate = t
-		} else if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if t, ok := parseSessionDate(s); ok {
 			msg.SessionDate = t
+		} else {
+			validationErr.add("sessionDate", ErrCodeInvalidFormat, s)
 		}
 	}
 
@@ -68,21 +107,88 @@ func ParseFromRedisValues(values map[string]interface{}) (*PomodoroPatternClassi
 	if s := getString("duration"); s != "" {
 		if v, err := strconv.ParseFloat(s, 64); err == nil {
 			msg.Duration = v
+		} else {
+			validationErr.add("duration", ErrCodeInvalidFormat, s)
 		}
+	} else {
+		validationErr.add("duration", ErrCodeRequired, "")
 	}
 
 	if s := getString("timestamp"); s != "" {
 		if v, err := strconv.ParseFloat(s, 64); err == nil {
 			msg.Timestamp = v
+		} else {
+			validationErr.add("timestamp", ErrCodeInvalidFormat, s)
 		}
+	} else {
+		validationErr.add("timestamp", ErrCodeRequired, "")
 	}
 
 	// Parse bool field
 	if s := getString("isEnd"); s != "" {
 		if v, err := strconv.ParseBool(s); err == nil {
 			msg.IsEnd = v
+		} else {
+			validationErr.add("isEnd", ErrCodeInvalidFormat, s)
 		}
 	}
 
-	return msg, nil
+	return msg
+}
+
+// Validate checks required fields, ObjectID formats and numeric bounds
+func (m *PomodoroPatternClassifyMessage) Validate() error {
+	validationErr := &ValidationError{}
+	m.validate(validationErr, time.Now())
+	return validationErr.orNil()
+}
+
+// validate only reports fields that have not already failed to parse
+func (m *PomodoroPatternClassifyMessage) validate(validationErr *ValidationError, now time.Time) {
+	if m.UserID == "" {
+		validationErr.add("userId", ErrCodeRequired, "")
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"categorizedDataId", m.CategorizedDataID},
+		{"pomodoroUsageLogId", m.PomodoroUsageLogID},
+	} {
+		if value := field.value; value == "" {
+			validationErr.add(field.name, ErrCodeRequired, "")
+		} else if _, err := primitive.ObjectIDFromHex(value); err != nil {
+			validationErr.add(field.name, ErrCodeInvalidObjectID, value)
+		}
+	}
+
+	if m.SessionDate.IsZero() && !validationErr.hasField("sessionDate") {
+		validationErr.add("sessionDate", ErrCodeRequired, "")
+	}
+
+	if !validationErr.hasField("duration") && (m.Duration < 0 || m.Duration > MaxUsageDuration) {
+		validationErr.add("duration", ErrCodeOutOfRange, fmt.Sprintf("must be between 0 and %.0f", MaxUsageDuration))
+	}
+
+	if m.Timezone != "" && !isKnownTimezone(m.Timezone) {
+		validationErr.add("timezone", ErrCodeInvalidFormat, m.Timezone)
+	}
+
+	maxTimestamp := float64(now.Add(maxTimestampClockSkew).Unix())
+	if !validationErr.hasField("timestamp") && (m.Timestamp < minTimestamp || m.Timestamp > maxTimestamp) {
+		validationErr.add("timestamp", ErrCodeOutOfRange, strconv.FormatFloat(m.Timestamp, 'f', -1, 64))
+	}
+}
+
+// knownTimezones caches the timezone names that load, since LoadLocation reads the tzdata files on every call.
+// Unknown names are not cached so arbitrary client input cannot grow it.
+var knownTimezones sync.Map
+
+func isKnownTimezone(name string) bool {
+	if _, ok := knownTimezones.Load(name); ok {
+		return true
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return false
+	}
+	knownTimezones.Store(name, struct{}{})
+	return true
 }
@@ -0,0 +1,234 @@
+package message
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testUserID      = "user-1"
+	testObjectID    = "65f1a2b3c4d5e6f708192a3b"
+	testTimestamp   = "1740787200" // 2025-03-01T00:00:00Z
+	testSessionDate = "2025-03-01"
+)
+
+func validFlatValues() map[string]interface{} {
+	return map[string]interface{}{
+		"userId":             testUserID,
+		"categorizedDataId":  testObjectID,
+		"pomodoroUsageLogId": testObjectID,
+		"app":                "Code",
+		"session":            "2",
+		"sessionDate":        testSessionDate,
+		"sessionMinutes":     "25",
+		"duration":           "120.5",
+		"timestamp":          testTimestamp,
+		"isEnd":              "true",
+		"timezone":           "Asia/Seoul",
+	}
+}
+
+// fieldErrors lists err's field errors as "field:code"
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error %v is not a *ValidationError", err)
+	}
+	fields := make([]string, 0, len(validationErr.Errors))
+	for _, fieldErr := range validationErr.Errors {
+		fields = append(fields, fieldErr.Field+":"+fieldErr.Code)
+	}
+	return fields
+}
+
+func TestParseFromRedisValuesFlat(t *testing.T) {
+	msg, err := ParseFromRedisValues(validFlatValues())
+	if err != nil {
+		t.Fatalf("ParseFromRedisValues: %v", err)
+	}
+
+	want := PomodoroPatternClassifyMessage{
+		UserID:             testUserID,
+		CategorizedDataID:  testObjectID,
+		PomodoroUsageLogID: testObjectID,
+		App:                "Code",
+		Session:            2,
+		SessionDate:        time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		SessionMinutes:     25,
+		Duration:           120.5,
+		Timestamp:          1740787200,
+		IsEnd:              true,
+		Timezone:           "Asia/Seoul",
+	}
+	if *msg != want {
+		t.Errorf("message = %+v, want %+v", *msg, want)
+	}
+}
+
+func TestParseFromRedisValuesFlatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change map[string]interface{}
+		remove []string
+		want   []string
+	}{
+		{
+			name:   "RFC3339 session date",
+			change: map[string]interface{}{"sessionDate": "2025-03-01T09:00:00+09:00"},
+		},
+		{
+			name:   "unparsable numbers and bool",
+			change: map[string]interface{}{"session": "two", "sessionMinutes": "x", "duration": "long", "isEnd": "maybe"},
+			want:   []string{"session:invalid_format", "sessionMinutes:invalid_format", "duration:invalid_format", "isEnd:invalid_format"},
+		},
+		{
+			name:   "unparsable session date is not also reported as missing",
+			change: map[string]interface{}{"sessionDate": "01/03/2025"},
+			want:   []string{"sessionDate:invalid_format"},
+		},
+		{
+			name:   "missing fields",
+			remove: []string{"userId", "categorizedDataId", "sessionDate", "duration", "timestamp"},
+			want:   []string{"duration:required", "timestamp:required", "userId:required", "categorizedDataId:required", "sessionDate:required"},
+		},
+		{
+			name:   "non-string value counts as missing",
+			change: map[string]interface{}{"userId": 42},
+			want:   []string{"userId:required"},
+		},
+		{
+			name:   "invalid ObjectIDs",
+			change: map[string]interface{}{"categorizedDataId": "abc", "pomodoroUsageLogId": "zz1a2b3c4d5e6f708192a3bc"},
+			want:   []string{"categorizedDataId:invalid_object_id", "pomodoroUsageLogId:invalid_object_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := validFlatValues()
+			for key, value := range tt.change {
+				values[key] = value
+			}
+			for _, key := range tt.remove {
+				delete(values, key)
+			}
+
+			_, err := ParseFromRedisValues(values)
+			if got := fieldErrors(t, err); !slices.Equal(got, tt.want) {
+				t.Errorf("field errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	valid := func() *PomodoroPatternClassifyMessage {
+		return &PomodoroPatternClassifyMessage{
+			UserID:             testUserID,
+			CategorizedDataID:  testObjectID,
+			PomodoroUsageLogID: testObjectID,
+			SessionDate:        time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			Duration:           60,
+			Timestamp:          float64(now.Unix()),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *PomodoroPatternClassifyMessage)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(m *PomodoroPatternClassifyMessage) {},
+		},
+		{
+			name:   "zero duration",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Duration = 0 },
+		},
+		{
+			name:   "longest duration",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Duration = MaxUsageDuration },
+		},
+		{
+			name:   "negative duration",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Duration = -1 },
+			want:   []string{"duration:out_of_range"},
+		},
+		{
+			name:   "duration too long",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Duration = MaxUsageDuration + 1 },
+			want:   []string{"duration:out_of_range"},
+		},
+		{
+			name:   "earliest timestamp",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Timestamp = minTimestamp },
+		},
+		{
+			name:   "timestamp before 2020",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Timestamp = minTimestamp - 1 },
+			want:   []string{"timestamp:out_of_range"},
+		},
+		{
+			name:   "timestamp within clock skew",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Timestamp = float64(now.Add(maxTimestampClockSkew).Unix()) },
+		},
+		{
+			name: "timestamp beyond clock skew",
+			modify: func(m *PomodoroPatternClassifyMessage) {
+				m.Timestamp = float64(now.Add(maxTimestampClockSkew).Unix() + 1)
+			},
+			want: []string{"timestamp:out_of_range"},
+		},
+		{
+			name:   "known timezone",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Timezone = "America/New_York" },
+		},
+		{
+			name:   "unknown timezone",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.Timezone = "Mars/Olympus" },
+			want:   []string{"timezone:invalid_format"},
+		},
+		{
+			name:   "missing session date",
+			modify: func(m *PomodoroPatternClassifyMessage) { m.SessionDate = time.Time{} },
+			want:   []string{"sessionDate:required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := valid()
+			tt.modify(msg)
+
+			validationErr := &ValidationError{}
+			msg.validate(validationErr, now)
+			if got := fieldErrors(t, validationErr.orNil()); !slices.Equal(got, tt.want) {
+				t.Errorf("field errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsKnownTimezoneCachesOnlyValidNames(t *testing.T) {
+	if !isKnownTimezone("Europe/Berlin") || !isKnownTimezone("Europe/Berlin") {
+		t.Error("Europe/Berlin is not known")
+	}
+	if _, ok := knownTimezones.Load("Europe/Berlin"); !ok {
+		t.Error("Europe/Berlin was not cached")
+	}
+
+	if isKnownTimezone("Not/AZone") {
+		t.Error("Not/AZone is known")
+	}
+	if _, ok := knownTimezones.Load("Not/AZone"); ok {
+		t.Error("Not/AZone was cached")
+	}
+}
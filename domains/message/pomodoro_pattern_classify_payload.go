@@ -0,0 +1,161 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// PayloadField carries the whole message encoded as a single field instead of flattened string fields
+	PayloadField     = "payload"
+	ContentTypeField = "contentType"
+
+	ContentTypeJSON    = "application/json"
+	ContentTypeMsgPack = "application/msgpack"
+
+	ErrCodeUnsupported = "unsupported"
+)
+
+// payloadDecoder decodes one schema version of an encoded payload
+type payloadDecoder func(raw []byte, validationErr *ValidationError) *PomodoroPatternClassifyMessage
+
+// pomodoroPayloadDecoders maps schemaVersion to its decoder; add a new entry instead of changing an old one
+var pomodoroPayloadDecoders = map[int]payloadDecoder{
+	1: decodePomodoroPayloadV1,
+}
+
+// pomodoroPayloadV1 is the first payload schema, mirroring the flattened stream fields with native JSON types
+type pomodoroPayloadV1 struct {
+	UserID             string   `json:"userId"`
+	CategorizedDataID  string   `json:"categorizedDataId"`
+	PomodoroUsageLogID string   `json:"pomodoroUsageLogId"`
+	URL                string   `json:"url"`
+	Title              string   `json:"title"`
+	App                string   `json:"app"`
+	Session            int      `json:"session"`
+	SessionDate        string   `json:"sessionDate"`
+	SessionMinutes     int      `json:"sessionMinutes"`
+	Duration           *float64 `json:"duration"`
+	Timestamp          *float64 `json:"timestamp"`
+	IsEnd              bool     `json:"isEnd"`
+	Timezone           string   `json:"timezone"`
+}
+
+// parsePayload decodes the payload field according to its content type and schema version.
+// It returns nil when the payload cannot be decoded at all.
+func parsePayload(values map[string]interface{}, validationErr *ValidationError) *PomodoroPatternClassifyMessage {
+	raw, ok := values[PayloadField].(string)
+	if !ok || raw == "" {
+		validationErr.add(PayloadField, ErrCodeRequired, "")
+		return nil
+	}
+
+	contentType, _ := values[ContentTypeField].(string)
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	if contentType != ContentTypeJSON {
+		// MessagePack decoding is reserved for a later schema rollout
+		validationErr.add(ContentTypeField, ErrCodeUnsupported, contentType)
+		return nil
+	}
+
+	var envelope struct {
+		SchemaVersion *int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+		validationErr.add(PayloadField, ErrCodeInvalidFormat, err.Error())
+		return nil
+	}
+
+	version := 1
+	if envelope.SchemaVersion != nil {
+		version = *envelope.SchemaVersion
+	}
+
+	decode, ok := pomodoroPayloadDecoders[version]
+	if !ok {
+		validationErr.add("schemaVersion", ErrCodeUnsupported, strconv.Itoa(version))
+		return nil
+	}
+	return decode([]byte(raw), validationErr)
+}
+
+// PayloadUserID decodes only the user of a JSON payload, skipping the parse and validation of everything else.
+// It returns "" when there is no readable user; ParseFromRedisValues reports why.
+func PayloadUserID(values map[string]interface{}) string {
+	raw, _ := values[PayloadField].(string)
+	if contentType, _ := values[ContentTypeField].(string); raw == "" || contentType != "" && contentType != ContentTypeJSON {
+		return ""
+	}
+
+	var payload struct {
+		UserID string `json:"userId"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return ""
+	}
+	return payload.UserID
+}
+
+func decodePomodoroPayloadV1(raw []byte, validationErr *ValidationError) *PomodoroPatternClassifyMessage {
+	var payload pomodoroPayloadV1
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			validationErr.add(PayloadField, ErrCodeInvalidFormat, err.Error())
+			return nil
+		}
+		// The remaining fields are still decoded, so keep going to report them as well
+		validationErr.add(typeErr.Field, ErrCodeInvalidFormat, fmt.Sprintf("expected %s", typeErr.Type))
+	}
+
+	msg := &PomodoroPatternClassifyMessage{
+		UserID:             payload.UserID,
+		CategorizedDataID:  payload.CategorizedDataID,
+		PomodoroUsageLogID: payload.PomodoroUsageLogID,
+		URL:                payload.URL,
+		Title:              payload.Title,
+		App:                payload.App,
+		Session:            payload.Session,
+		SessionMinutes:     payload.SessionMinutes,
+		IsEnd:              payload.IsEnd,
+		Timezone:           payload.Timezone,
+	}
+
+	if payload.SessionDate != "" {
+		if t, ok := parseSessionDate(payload.SessionDate); ok {
+			msg.SessionDate = t
+		} else {
+			validationErr.add("sessionDate", ErrCodeInvalidFormat, payload.SessionDate)
+		}
+	}
+
+	if payload.Duration != nil {
+		msg.Duration = *payload.Duration
+	} else if !validationErr.hasField("duration") {
+		validationErr.add("duration", ErrCodeRequired, "")
+	}
+
+	if payload.Timestamp != nil {
+		msg.Timestamp = *payload.Timestamp
+	} else if !validationErr.hasField("timestamp") {
+		validationErr.add("timestamp", ErrCodeRequired, "")
+	}
+
+	return msg
+}
+
+// parseSessionDate accepts a date-only value or a full RFC3339 timestamp
+func parseSessionDate(s string) (time.Time, bool) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
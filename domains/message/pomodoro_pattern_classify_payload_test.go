@@ -0,0 +1,152 @@
+package message
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+const validPayload = `{"schemaVersion":1,"userId":"user-1","categorizedDataId":"65f1a2b3c4d5e6f708192a3b",` +
+	`"pomodoroUsageLogId":"65f1a2b3c4d5e6f708192a3b","app":"Code","session":2,"sessionDate":"2025-03-01",` +
+	`"sessionMinutes":25,"duration":120.5,"timestamp":1740787200,"isEnd":true,"timezone":"Asia/Seoul"}`
+
+func TestParseFromRedisValuesPayload(t *testing.T) {
+	msg, err := ParseFromRedisValues(map[string]interface{}{PayloadField: validPayload, ContentTypeField: ContentTypeJSON})
+	if err != nil {
+		t.Fatalf("ParseFromRedisValues: %v", err)
+	}
+
+	flat, err := ParseFromRedisValues(validFlatValues())
+	if err != nil {
+		t.Fatalf("ParseFromRedisValues(flat): %v", err)
+	}
+	if *msg != *flat {
+		t.Errorf("payload message = %+v, want the same as the flat message %+v", *msg, *flat)
+	}
+}
+
+func TestParseFromRedisValuesPayloadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "schema version defaults to 1",
+			values: map[string]interface{}{PayloadField: `{"userId":"user-1","categorizedDataId":"65f1a2b3c4d5e6f708192a3b","pomodoroUsageLogId":"65f1a2b3c4d5e6f708192a3b","sessionDate":"2025-03-01","duration":0,"timestamp":1740787200}`},
+		},
+		{
+			name:   "empty payload",
+			values: map[string]interface{}{PayloadField: ""},
+			want:   []string{"payload:required"},
+		},
+		{
+			name:   "non-string payload",
+			values: map[string]interface{}{PayloadField: 42},
+			want:   []string{"payload:required"},
+		},
+		{
+			name:   "msgpack is not supported yet",
+			values: map[string]interface{}{PayloadField: validPayload, ContentTypeField: ContentTypeMsgPack},
+			want:   []string{"contentType:unsupported"},
+		},
+		{
+			name:   "malformed JSON",
+			values: map[string]interface{}{PayloadField: `{"userId":`},
+			want:   []string{"payload:invalid_format"},
+		},
+		{
+			name:   "unknown schema version",
+			values: map[string]interface{}{PayloadField: `{"schemaVersion":99}`},
+			want:   []string{"schemaVersion:unsupported"},
+		},
+		{
+			name:   "wrong field type is reported along with the remaining fields",
+			values: map[string]interface{}{PayloadField: `{"userId":"user-1","categorizedDataId":"65f1a2b3c4d5e6f708192a3b","pomodoroUsageLogId":"65f1a2b3c4d5e6f708192a3b","sessionDate":"2025-03-01","duration":"120","timestamp":1740787200}`},
+			want:   []string{"duration:invalid_format"},
+		},
+		{
+			name:   "missing numbers and invalid date",
+			values: map[string]interface{}{PayloadField: `{"userId":"user-1","categorizedDataId":"65f1a2b3c4d5e6f708192a3b","pomodoroUsageLogId":"65f1a2b3c4d5e6f708192a3b","sessionDate":"March 1st"}`},
+			want:   []string{"sessionDate:invalid_format", "duration:required", "timestamp:required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFromRedisValues(tt.values)
+			if got := fieldErrors(t, err); !slices.Equal(got, tt.want) {
+				t.Errorf("field errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayloadUserID(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   string
+	}{
+		{
+			name:   "JSON payload",
+			values: map[string]interface{}{PayloadField: validPayload},
+			want:   "user-1",
+		},
+		{
+			name:   "explicit JSON content type",
+			values: map[string]interface{}{PayloadField: validPayload, ContentTypeField: ContentTypeJSON},
+			want:   "user-1",
+		},
+		{
+			name:   "other fields are not validated",
+			values: map[string]interface{}{PayloadField: `{"schemaVersion":99,"userId":"user-1","duration":"long"}`},
+			want:   "user-1",
+		},
+		{
+			name:   "msgpack payload",
+			values: map[string]interface{}{PayloadField: validPayload, ContentTypeField: ContentTypeMsgPack},
+		},
+		{
+			name:   "malformed JSON",
+			values: map[string]interface{}{PayloadField: `{"userId":`},
+		},
+		{
+			name:   "wrong user type",
+			values: map[string]interface{}{PayloadField: `{"userId":42}`},
+		},
+		{
+			name:   "flat values",
+			values: validFlatValues(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PayloadUserID(tt.values); got != tt.want {
+				t.Errorf("PayloadUserID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSessionDate(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   time.Time
+		wantOK bool
+	}{
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"2025-03-01T09:00:00Z", time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), true},
+		{"2025-03-01T09:00:00+09:00", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"2025-02-30", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseSessionDate(tt.in)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("parseSessionDate(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,28 +1,72 @@
 package message
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 )
 
+// SessionScoreMessageVersion is bumped whenever fields are added so the Spring consumer can migrate
+const SessionScoreMessageVersion = 2
+
 type SessionScoreMessage struct {
-	UserID      string    `json:"userId"`
-	SessionDate time.Time `json:"sessionDate"`
-	Session     int       `json:"session"`
+	SchemaVersion int `json:"schemaVersion" bson:"schemaVersion"`
+	// EventID identifies the session; relays may deliver an event more than once, so consumers dedupe on it
+	EventID     string    `json:"eventId" bson:"eventId"`
+	UserID      string    `json:"userId" bson:"userId"`
+	SessionDate time.Time `json:"sessionDate" bson:"sessionDate"`
+	Session     int       `json:"session" bson:"session"`
+	// IsPartial is set when the event was published before every usage log of the session was categorized
+	IsPartial bool `json:"isPartial" bson:"isPartial"`
+
+	CategorySeconds    map[string]float64 `json:"categorySeconds" bson:"categorySeconds"`
+	WorkSeconds        float64            `json:"workSeconds" bson:"workSeconds"`
+	NonWorkSeconds     float64            `json:"nonWorkSeconds" bson:"nonWorkSeconds"`
+	DistinctApps       int                `json:"distinctApps" bson:"distinctApps"`
+	ContextSwitches    int                `json:"contextSwitches" bson:"contextSwitches"`
+	LLMClassifiedShare float64            `json:"llmClassifiedShare" bson:"llmClassifiedShare"`
 }
 
 func NewSessionScoreMessage(userID string, sessionDate time.Time, session int) *SessionScoreMessage {
 	return &SessionScoreMessage{
-		UserID:      userID,
-		SessionDate: sessionDate,
-		Session:     session,
+		SchemaVersion:   SessionScoreMessageVersion,
+		EventID:         SessionEventID(userID, sessionDate, session),
+		UserID:          userID,
+		SessionDate:     sessionDate,
+		Session:         session,
+		CategorySeconds: make(map[string]float64),
 	}
 }
 
+// SessionEventID is the dedupe key of a session's score event
+func SessionEventID(userID string, sessionDate time.Time, session int) string {
+	return fmt.Sprintf("%s:%s:%d", userID, sessionDate.Format("2006-01-02"), session)
+}
+
+func (m *SessionScoreMessage) MarkPartial() {
+	m.IsPartial = true
+}
+
 // ToRedisValues converts the message to Redis stream values
 func (m *SessionScoreMessage) ToRedisValues() map[string]interface{} {
+	categorySeconds, err := json.Marshal(m.CategorySeconds)
+	if err != nil {
+		categorySeconds = []byte("{}")
+	}
+
 	return map[string]interface{}{
-		"userId":      m.UserID,
-		"sessionDate": m.SessionDate.Format("2006-01-02"),
-		"session":     m.Session,
+		"schemaVersion":      strconv.Itoa(m.SchemaVersion),
+		"eventId":            m.EventID,
+		"userId":             m.UserID,
+		"sessionDate":        m.SessionDate.Format("2006-01-02"),
+		"session":            m.Session,
+		"isPartial":          strconv.FormatBool(m.IsPartial),
+		"categorySeconds":    string(categorySeconds),
+		"workSeconds":        strconv.FormatFloat(m.WorkSeconds, 'f', -1, 64),
+		"nonWorkSeconds":     strconv.FormatFloat(m.NonWorkSeconds, 'f', -1, 64),
+		"distinctApps":       strconv.Itoa(m.DistinctApps),
+		"contextSwitches":    strconv.Itoa(m.ContextSwitches),
+		"llmClassifiedShare": strconv.FormatFloat(m.LLMClassifiedShare, 'f', 4, 64),
 	}
 }
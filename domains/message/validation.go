@@ -0,0 +1,61 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation error codes, stable so DLQ consumers can group failures
+const (
+	ErrCodeRequired        = "required"
+	ErrCodeInvalidFormat   = "invalid_format"
+	ErrCodeInvalidObjectID = "invalid_object_id"
+	ErrCodeOutOfRange      = "out_of_range"
+)
+
+// FieldError describes why a single field of a message was rejected
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (e FieldError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Code)
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.Field, e.Code, e.Detail)
+}
+
+// ValidationError collects every field error of a message so producers can fix them in one go
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Error())
+	}
+	return "invalid message: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(field, code, detail string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Detail: detail})
+}
+
+func (e *ValidationError) hasField(field string) bool {
+	for _, fieldErr := range e.Errors {
+		if fieldErr.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -3,31 +3,37 @@ package core
 import (
 	"context"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"pomocore-data/shared/common/metrics"
+	"pomocore-data/shared/common/tracing"
 )
 
 type LLMClient struct {
 	client  *openai.Client
+	model   string
 	timeout time.Duration
 }
 
-func NewLLMClient() *LLMClient {
-	apiKey := os.Getenv("OPENAI_API_KEY")
+func NewLLMClient(apiKey, model string, timeout time.Duration) *LLMClient {
 	if apiKey == "" {
 		return nil // Return nil if no API key is set
 	}
 
 	return &LLMClient{
 		client:  openai.NewClient(apiKey),
-		timeout: 30 * time.Second,
+		model:   model,
+		timeout: timeout,
 	}
 }
 
-func (l *LLMClient) ClassifyUsage(app, title, url string) (string, error) {
+// ClassifyUsage asks the LLM for a category; the per-call timeout never outlives the caller's context
+func (l *LLMClient) ClassifyUsage(ctx context.Context, app, title, url string) (string, error) {
 	if l == nil || l.client == nil {
 		return "", fmt.Errorf("LLM client not initialized")
 	}
@@ -35,15 +41,21 @@ func (l *LLMClient) ClassifyUsage(app, title, url string) (string, error) {
 	prompt := l.buildPrompt(app, title, url)
 	systemPrompt := l.buildSystemPrompt()
 
-	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
 	defer cancel()
 
 	cnt := 0
 	var err error
 	var resp openai.ChatCompletionResponse
 	for cnt < 5 {
-		resp, err = l.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-			Model:       openai.GPT4Dot1,
+		attemptCtx, span := tracing.Tracer().Start(ctx, "llm.chat_completion",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("gen_ai.request.model", l.model),
+				attribute.Int("llm.attempt", cnt+1)))
+		start := time.Now()
+		resp, err = l.client.CreateChatCompletion(attemptCtx, openai.ChatCompletionRequest{
+			Model:       l.model,
 			Temperature: 0.1,
 			Messages: []openai.ChatCompletionMessage{
 				{
@@ -57,11 +69,26 @@ func (l *LLMClient) ClassifyUsage(app, title, url string) (string, error) {
 			},
 		})
 		cnt++
-		if err == nil {
+		metrics.LLMRequestDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.LLMErrors.Inc()
+			tracing.RecordError(span, err)
+		} else {
+			metrics.LLMTokens.WithLabelValues("prompt").Add(float64(resp.Usage.PromptTokens))
+			metrics.LLMTokens.WithLabelValues("completion").Add(float64(resp.Usage.CompletionTokens))
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", resp.Usage.PromptTokens),
+				attribute.Int("gen_ai.usage.output_tokens", resp.Usage.CompletionTokens))
+		}
+		span.End()
+		if err == nil || ctx.Err() != nil {
 			break
 		}
 
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+		case <-time.After(1 * time.Second):
+		}
 	}
 
 	if err != nil {
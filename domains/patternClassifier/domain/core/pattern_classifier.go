@@ -1,14 +1,18 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"pomocore-data/domains/patternClassifier/domain/structure"
 	"pomocore-data/infrastructure/mongoDB/model"
 	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+	"pomocore-data/shared/common/tracing"
 	"strings"
 	"sync"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -18,13 +22,15 @@ type PatternClassifier struct {
 	cache           *sync.Map
 	llmClient       *LLMClient
 	initialized     bool
+	patternCount    int
 	categoryToIdMap map[string]primitive.ObjectID
 }
 
-func NewPatternClassifier() *PatternClassifier {
+// NewPatternClassifier falls back to the LLM for usage no pattern matches; llmClient may be nil to skip that step
+func NewPatternClassifier(llmClient *LLMClient) *PatternClassifier {
 	return &PatternClassifier{
 		cache:       &sync.Map{},
-		llmClient:   NewLLMClient(),
+		llmClient:   llmClient,
 		initialized: false,
 	}
 }
@@ -32,9 +38,15 @@ func NewPatternClassifier() *PatternClassifier {
 func (p *PatternClassifier) Initialize(patterns []model.CategoryPattern) {
 	p.appTrie = p.initAppTrie(patterns)
 	p.urlTrie = p.initUrlAhoCorasick(patterns)
+	p.patternCount = len(patterns)
 	p.initialized = true
 }
 
+// PatternCount is the number of category patterns loaded by Initialize
+func (p *PatternClassifier) PatternCount() int {
+	return p.patternCount
+}
+
 func (p *PatternClassifier) initAppTrie(patterns []model.CategoryPattern) *structure.Trie {
 	trie := structure.NewTrie()
 	for _, pattern := range patterns {
@@ -56,32 +68,44 @@ func (p *PatternClassifier) initUrlAhoCorasick(patterns []model.CategoryPattern)
 	return ac
 }
 
-func (p *PatternClassifier) Classify(app, title, url string) (string, bool) {
+func (p *PatternClassifier) Classify(ctx context.Context, app, title, url string) (string, bool) {
 	if !p.initialized {
 		logger.Fatal("PatternClassifier not initialized")
 	}
-	app = strings.ToLower(app)
 
+	ctx, span := tracing.Tracer().Start(ctx, "classifier.classify")
+	defer span.End()
+
+	category, isLLMBased, source := p.classify(ctx, strings.ToLower(app), title, url)
+	metrics.Classifications.WithLabelValues(source).Inc()
+	span.SetAttributes(
+		attribute.String("classification.source", source),
+		attribute.String("classification.category", category))
+	return category, isLLMBased
+}
+
+// classify tries the app trie, the URL automaton, the cache and finally the LLM, reporting which one answered
+func (p *PatternClassifier) classify(ctx context.Context, app, title, url string) (string, bool, string) {
 	var category string
 
 	if category = p.classifyFromApp(app); category != "" {
-		return category, false
+		return category, false, metrics.SourceAppTrie
 	}
 
 	if category = p.classifyFromURL(url); category != "" {
-		return category, false
+		return category, false, metrics.SourceURL
 	}
 
 	query := getQuery(app, title, url)
 	if category = p.classifyFromCache(query); category != "" {
-		return category, true
+		return category, true, metrics.SourceCache
 	}
 
-	if category = p.classifyFromLLM(app, title, url); category != "" {
-		return p.putCache(query, category), true
+	if category = p.classifyFromLLM(ctx, app, title, url); category != "" {
+		return p.putCache(query, category), true, metrics.SourceLLM
 	}
 
-	return "", true
+	return "", true, metrics.SourceFailed
 }
 
 func (p *PatternClassifier) ClassifyFromApp(app string) string {
@@ -113,9 +137,9 @@ func (p *PatternClassifier) classifyFromCache(query string) string {
 	return ""
 }
 
-func (p *PatternClassifier) classifyFromLLM(app, title, url string) string {
+func (p *PatternClassifier) classifyFromLLM(ctx context.Context, app, title, url string) string {
 	if p.llmClient == nil {
-		logger.Warn("LLM client is nil - OPENAI_API_KEY not set?")
+		logger.Warn("LLM client is nil - llm.apiKey (OPENAI_API_KEY) not set?")
 		return ""
 	}
 
@@ -124,7 +148,7 @@ func (p *PatternClassifier) classifyFromLLM(app, title, url string) string {
 		zap.String("title", title),
 		zap.String("url", url))
 
-	category, err := p.llmClient.ClassifyUsage(app, title, url)
+	category, err := p.llmClient.ClassifyUsage(ctx, app, title, url)
 	if err != nil {
 		logger.Error("LLM classification failed", logger.WithError(err))
 		return ""
@@ -0,0 +1,25 @@
+package port
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"time"
+)
+
+type DeferredClassificationRepositoryPort interface {
+	// SaveBatch stores items that could not be classified; items already queued for the same usage log are left untouched
+	SaveBatch(ctx context.Context, items []*model.DeferredClassification) error
+
+	// ClaimDue leases up to limit pending items whose retry time has passed so that only one worker retries them
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.DeferredClassification, error)
+
+	MarkResolved(ctx context.Context, id primitive.ObjectID, category string) error
+	Reschedule(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id primitive.ObjectID, attempts int, lastError string) error
+
+	// CountPendingBySession counts items of a session still waiting for a retry
+	CountPendingBySession(ctx context.Context, userID string, sessionDate time.Time, session int) (int64, error)
+
+	FindResolvedBySession(ctx context.Context, userID string, sessionDate time.Time, session int) ([]*model.DeferredClassification, error)
+}
@@ -17,4 +17,10 @@ type PomodoroUsageLogRepositoryPort interface {
 	SaveBatch(ctx context.Context, logs []*model.PomodoroUsageLog) ([]*primitive.ObjectID, error)
 	UpdateCategorizedDataIDsBatch(ctx context.Context, usageLogToCategorizedDataMap map[string]primitive.ObjectID) error
 	UpdateCategoryIDsBatch(ctx context.Context, usageLogToCategoryIDMap map[string]primitive.ObjectID) error
+
+	// CountUncategorizedBySession counts usage logs of a session that have not been assigned a category yet
+	CountUncategorizedBySession(ctx context.Context, userID string, sessionDate time.Time, session int) (int64, error)
+
+	// ForEach streams every usage log ordered by user, session date and timestamp; used by the leaderboard rebuild
+	ForEach(ctx context.Context, fn func(log *model.PomodoroUsageLog) error) error
 }
@@ -0,0 +1,12 @@
+package port
+
+import (
+	"context"
+
+	"pomocore-data/domains/message"
+)
+
+type SessionScoreEventPort interface {
+	// Publish emits a session score event for the Spring server
+	Publish(ctx context.Context, msg *message.SessionScoreMessage) error
+}
@@ -0,0 +1,34 @@
+package port
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"pomocore-data/domains/message"
+	"pomocore-data/domains/pomodoro/domain"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"time"
+)
+
+type SessionScoreOutboxRepositoryPort interface {
+	// RecordActivities adds classified activities to their sessions' outbox rows
+	RecordActivities(ctx context.Context, activities []*domain.SessionActivity) error
+
+	// MarkEnded flags sessions whose last usage log has been processed
+	MarkEnded(ctx context.Context, sessionScoreMsgs []*message.SessionScoreMessage) error
+
+	FindAwaiting(ctx context.Context, limit int) ([]*model.SessionScoreOutbox, error)
+	FindAwaitingByEventIDs(ctx context.Context, eventIDs []string) ([]*model.SessionScoreOutbox, error)
+
+	// MarkReady stores the finished event; it is a no-op if the row has already left the pending state
+	MarkReady(ctx context.Context, id primitive.ObjectID, payload *message.SessionScoreMessage) error
+
+	// ClaimReady leases ready rows for publishing so that replicas do not relay the same row concurrently.
+	// Rows already claimed maxAttempts times are left for ParkExhausted.
+	ClaimReady(ctx context.Context, now time.Time, lease time.Duration, maxAttempts int, limit int) ([]*model.SessionScoreOutbox, error)
+
+	// ParkExhausted moves ready rows whose last allowed attempt has run out to the failed status and returns how many it moved
+	ParkExhausted(ctx context.Context, now time.Time, maxAttempts int) (int64, error)
+
+	MarkSent(ctx context.Context, id primitive.ObjectID) error
+	MarkPublishFailed(ctx context.Context, id primitive.ObjectID, lastError string) error
+}
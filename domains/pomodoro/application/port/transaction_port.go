@@ -0,0 +1,11 @@
+package port
+
+import (
+	"context"
+)
+
+type TransactionPort interface {
+	// WithinTransaction runs fn atomically; repositories must be called with the context passed to fn.
+	// Without transaction support fn runs as is and may stop halfway, so its writes must be safe to repeat.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
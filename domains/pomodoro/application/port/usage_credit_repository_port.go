@@ -0,0 +1,32 @@
+package port
+
+import (
+	"context"
+	"pomocore-data/domains/pomodoro/domain"
+	"time"
+)
+
+// UsageCreditWindow selects a user's credited usage overlapping [From, To] in unix seconds
+type UsageCreditWindow struct {
+	UserID string
+	From   float64
+	To     float64
+}
+
+// UsageCreditSession selects a user's credited usage of one session
+type UsageCreditSession struct {
+	UserID      string
+	SessionDate time.Time
+	Session     int
+}
+
+type UsageCreditRepositoryPort interface {
+	FindInWindows(ctx context.Context, windows []UsageCreditWindow) ([]*domain.UsageCredit, error)
+
+	FindBySessions(ctx context.Context, sessions []UsageCreditSession) ([]*domain.UsageCredit, error)
+
+	FindByUsageLogIDs(ctx context.Context, usageLogIDs []string) ([]*domain.UsageCredit, error)
+
+	// SaveBatch stores new credits; credits already stored for the same usage log are left untouched
+	SaveBatch(ctx context.Context, credits []*domain.UsageCredit) error
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	categoryPatternUseCase "pomocore-data/domains/categoryPattern/application/useCase"
+	"pomocore-data/domains/leaderboard/application/port"
+	"pomocore-data/domains/leaderboard/domain"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	pomodoroUseCase "pomocore-data/domains/pomodoro/application/usecase"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"pomocore-data/shared/common/logger"
+)
+
+const uncategorizedCategory = "Uncategorized"
+
+type DeferredClassificationService struct {
+	patternClassifier      PatternClassifier
+	deferredRepo           pomodoroPort.DeferredClassificationRepositoryPort
+	categorizedDataRepo    pomodoroPort.CategorizedDataRepositoryPort
+	pomodoroUsageLogRepo   pomodoroPort.PomodoroUsageLogRepositoryPort
+	categoryPatternUseCase categoryPatternUseCase.CategoryPatternUseCase
+	leaderboardCache       port.LeaderboardCachePort
+	bucketing              *domain.Bucketing
+	batchSize              int
+	maxAttempts            int
+	baseBackoff            time.Duration
+	claimLease             time.Duration
+}
+
+func NewDeferredClassificationService(
+	patternClassifier PatternClassifier,
+	deferredRepo pomodoroPort.DeferredClassificationRepositoryPort,
+	categorizedDataRepo pomodoroPort.CategorizedDataRepositoryPort,
+	pomodoroUsageLogRepo pomodoroPort.PomodoroUsageLogRepositoryPort,
+	categoryPatternUseCase categoryPatternUseCase.CategoryPatternUseCase,
+	leaderboardCache port.LeaderboardCachePort,
+	bucketing *domain.Bucketing,
+) pomodoroUseCase.RetryDeferredClassificationUseCase {
+	return &DeferredClassificationService{
+		patternClassifier:      patternClassifier,
+		deferredRepo:           deferredRepo,
+		categorizedDataRepo:    categorizedDataRepo,
+		pomodoroUsageLogRepo:   pomodoroUsageLogRepo,
+		categoryPatternUseCase: categoryPatternUseCase,
+		leaderboardCache:       leaderboardCache,
+		bucketing:              bucketing,
+		batchSize:              50,
+		maxAttempts:            5,
+		baseBackoff:            time.Minute,
+		claimLease:             5 * time.Minute,
+	}
+}
+
+// RetryDue reclassifies deferred items and, on success, moves their minutes from the Uncategorized boards to the real category
+func (s *DeferredClassificationService) RetryDue(ctx context.Context) (int, error) {
+	items, err := s.deferredRepo.ClaimDue(ctx, time.Now(), s.claimLease, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deferred classifications: %w", err)
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	categoryToIdMap, err := s.categoryPatternUseCase.GetCategoryToIdMap(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load category mapping: %w", err)
+	}
+
+	resolved := make([]*model.DeferredClassification, 0, len(items))
+	resolvedCategories := make([]string, 0, len(items))
+	usageLogToCategoryIDMap := make(map[string]primitive.ObjectID)
+	categorizedDataToCategoryIDMap := make(map[string]primitive.ObjectID)
+	leaderboardUpdates := make([]*domain.LeaderboardEntry, 0, len(items)*2)
+
+	// Stop classifying once the lease runs out, since another worker may claim the items again
+	classifyCtx, cancel := context.WithTimeout(ctx, s.claimLease)
+	defer cancel()
+
+	for _, item := range items {
+		if classifyCtx.Err() != nil {
+			break
+		}
+
+		category, _ := s.patternClassifier.Classify(classifyCtx, item.App, item.Title, item.URL)
+		if category == "" {
+			s.scheduleNextAttempt(ctx, item, "classification returned no category")
+			continue
+		}
+
+		categoryID := categoryToIdMap[category]
+		if categoryID.IsZero() {
+			s.scheduleNextAttempt(ctx, item, fmt.Sprintf("no ObjectID found for category %q", category))
+			continue
+		}
+
+		usageLogToCategoryIDMap[item.PomodoroUsageLogID.Hex()] = categoryID
+		categorizedDataToCategoryIDMap[item.CategorizedDataID.Hex()] = categoryID
+
+		// The original pass credited Uncategorized, so move the minutes rather than adding them twice
+		if category != uncategorizedCategory {
+			// Items queued before the day was stored fall back to the configured timezone
+			day := item.LeaderboardDay
+			if day.IsZero() {
+				day = s.bucketing.Day(item.SessionDate, item.Timestamp, "")
+			}
+			leaderboardUpdates = append(leaderboardUpdates,
+				domain.NewLeaderboardEntry(item.ID.Hex()+":out", item.UserID, uncategorizedCategory, -item.Duration, item.Timestamp, day),
+				domain.NewLeaderboardEntry(item.ID.Hex()+":in", item.UserID, category, item.Duration, item.Timestamp, day),
+			)
+		}
+
+		resolved = append(resolved, item)
+		resolvedCategories = append(resolvedCategories, category)
+	}
+
+	if len(resolved) == 0 {
+		return 0, nil
+	}
+
+	if err := s.pomodoroUsageLogRepo.UpdateCategoryIDsBatch(ctx, usageLogToCategoryIDMap); err != nil {
+		return 0, fmt.Errorf("failed to update usage logs: %w", err)
+	}
+
+	if err := s.categorizedDataRepo.UpdateCategoryIDsBatch(ctx, categorizedDataToCategoryIDMap); err != nil {
+		return 0, fmt.Errorf("failed to update categorized data: %w", err)
+	}
+
+	if err := s.leaderboardCache.BatchIncreaseScore(ctx, leaderboardUpdates); err != nil {
+		return 0, fmt.Errorf("failed to move leaderboard scores: %w", err)
+	}
+
+	// An item that cannot be marked resolved is claimed again once its lease runs out; the leaderboard
+	// entries keep their IDs, so moving its minutes again is deduplicated
+	var markErrs []error
+	for i, item := range resolved {
+		if err := s.deferredRepo.MarkResolved(ctx, item.ID, resolvedCategories[i]); err != nil {
+			markErrs = append(markErrs, fmt.Errorf("failed to mark %s resolved: %w", item.ID.Hex(), err))
+		}
+	}
+	marked := len(resolved) - len(markErrs)
+
+	logger.Info("Resolved deferred classifications",
+		zap.Int("claimed", len(items)),
+		zap.Int("resolved", marked))
+	if len(markErrs) > 0 {
+		return marked, errors.Join(markErrs...)
+	}
+	return marked, nil
+}
+
+// scheduleNextAttempt backs off exponentially and gives up after maxAttempts, leaving the item Uncategorized
+func (s *DeferredClassificationService) scheduleNextAttempt(ctx context.Context, item *model.DeferredClassification, reason string) {
+	attempts := item.Attempts + 1
+
+	if attempts >= s.maxAttempts {
+		logger.Warn("Giving up on deferred classification",
+			zap.String("usage_log_id", item.PomodoroUsageLogID.Hex()),
+			zap.Int("attempts", attempts),
+			zap.String("reason", reason))
+		if err := s.deferredRepo.MarkFailed(ctx, item.ID, attempts, reason); err != nil {
+			logger.Error("Error marking deferred classification failed", logger.WithError(err))
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(s.baseBackoff * time.Duration(1<<(attempts-1)))
+	if err := s.deferredRepo.Reschedule(ctx, item.ID, attempts, nextAttemptAt, reason); err != nil {
+		logger.Error("Error rescheduling deferred classification", logger.WithError(err))
+	}
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	categoryPatternUseCase "pomocore-data/domains/categoryPattern/application/useCase"
+	"pomocore-data/domains/leaderboard/application/port"
+	leaderboardService "pomocore-data/domains/leaderboard/application/service"
+	"pomocore-data/domains/leaderboard/domain"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	pomodoroUseCase "pomocore-data/domains/pomodoro/application/usecase"
+	pomodoroDomain "pomocore-data/domains/pomodoro/domain"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"pomocore-data/shared/common/logger"
+)
+
+// rebuildChunkSize is how many usage logs share one lookup of their stored credits
+const rebuildChunkSize = 500
+
+type LeaderboardRebuildService struct {
+	pomodoroUsageLogRepo   pomodoroPort.PomodoroUsageLogRepositoryPort
+	usageCreditRepo        pomodoroPort.UsageCreditRepositoryPort
+	categoryPatternUseCase categoryPatternUseCase.CategoryPatternUseCase
+	leaderboardCache       port.LeaderboardCachePort
+	groupMemberships       *leaderboardService.GroupMembershipCache
+	bucketing              *domain.Bucketing
+	keyLayout              domain.KeyLayout
+}
+
+func NewLeaderboardRebuildService(
+	pomodoroUsageLogRepo pomodoroPort.PomodoroUsageLogRepositoryPort,
+	usageCreditRepo pomodoroPort.UsageCreditRepositoryPort,
+	categoryPatternUseCase categoryPatternUseCase.CategoryPatternUseCase,
+	leaderboardCache port.LeaderboardCachePort,
+	groupMemberships *leaderboardService.GroupMembershipCache,
+	bucketing *domain.Bucketing,
+	keyLayout domain.KeyLayout,
+) pomodoroUseCase.RebuildLeaderboardUseCase {
+	return &LeaderboardRebuildService{
+		pomodoroUsageLogRepo:   pomodoroUsageLogRepo,
+		usageCreditRepo:        usageCreditRepo,
+		categoryPatternUseCase: categoryPatternUseCase,
+		leaderboardCache:       leaderboardCache,
+		groupMemberships:       groupMemberships,
+		bucketing:              bucketing,
+		keyLayout:              keyLayout,
+	}
+}
+
+// Rebuild replays the amount and day stored in usage_credit for every usage log, so totals match what the
+// live pipeline credited, under the usage log's current category. Logs credited before credits were kept have
+// no stored credit; they are run through a usage ledger per user day and bucketed in the configured timezone.
+// Group boards use the current group memberships.
+//
+// Boards are replaced as a whole, so scores added by a running consumer while a board is built are lost;
+// stop the consumers before rebuilding. Only boards that get scores from the rebuild are replaced: a board whose
+// usage all moved elsewhere, e.g. to another category or day, keeps its old scores and must be deleted by hand.
+func (s *LeaderboardRebuildService) Rebuild(ctx context.Context, periods []domain.Period, dryRun bool) (pomodoroUseCase.RebuildLeaderboardResult, error) {
+	var result pomodoroUseCase.RebuildLeaderboardResult
+
+	idToCategory, err := s.categoryPatternUseCase.GetIdToCategoryMap(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to load categories: %w", err)
+	}
+	if err := s.groupMemberships.Refresh(ctx); err != nil {
+		return result, fmt.Errorf("failed to load group memberships: %w", err)
+	}
+
+	type board struct {
+		period domain.Period
+		scores map[string]float64
+	}
+	boards := make(map[string]*board)
+	var ledger *pomodoroDomain.UsageLedger
+	var ledgerUser string
+	var ledgerDate int64
+
+	// addUsageLog adds one usage log to its boards, preferring its stored credit
+	addUsageLog := func(usageLog *model.PomodoroUsageLog, stored *pomodoroDomain.UsageCredit) {
+		credit := stored
+		if credit == nil {
+			// Logs are ordered by user and session date, so one ledger per user day is enough to catch overlaps
+			if ledger == nil || usageLog.UserID != ledgerUser || usageLog.SessionDate.Unix() != ledgerDate {
+				ledger = pomodoroDomain.NewUsageLedger(nil)
+				ledgerUser = usageLog.UserID
+				ledgerDate = usageLog.SessionDate.Unix()
+			}
+			credit, _ = ledger.Credit(&pomodoroDomain.UsageCredit{
+				UserID:             usageLog.UserID,
+				PomodoroUsageLogID: usageLog.ID.Hex(),
+				SessionDate:        usageLog.SessionDate,
+				Session:            usageLog.Session,
+				SessionMinutes:     usageLog.SessionMinutes,
+				Start:              usageLog.Timestamp,
+				Duration:           usageLog.Duration,
+			})
+		} else {
+			result.StoredCredits++
+		}
+		if credit.Credited <= 0 {
+			return
+		}
+
+		category, ok := idToCategory[usageLog.CategoryID.Hex()]
+		if !ok {
+			category = uncategorizedCategory
+		}
+		day := credit.LeaderboardDay
+		if day.IsZero() {
+			day = s.bucketing.Day(usageLog.SessionDate, usageLog.Timestamp, "")
+		}
+
+		entry := domain.NewLeaderboardEntry("", usageLog.UserID, category, credit.Credited, usageLog.Timestamp, day)
+		entry.GroupIDs = s.groupMemberships.GroupIDs(usageLog.UserID)
+		for _, period := range periods {
+			for _, key := range entry.GetLeaderboardKeys(s.keyLayout, []domain.Period{period}) {
+				if boards[key] == nil {
+					boards[key] = &board{period: period, scores: make(map[string]float64)}
+				}
+				boards[key].scores[entry.UserID] += entry.Duration
+			}
+		}
+	}
+
+	chunk := make([]*model.PomodoroUsageLog, 0, rebuildChunkSize)
+	flush := func() error {
+		ids := make([]string, len(chunk))
+		for i, usageLog := range chunk {
+			ids[i] = usageLog.ID.Hex()
+		}
+		credits, err := s.usageCreditRepo.FindByUsageLogIDs(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("failed to read usage credits: %w", err)
+		}
+		creditByLog := make(map[string]*pomodoroDomain.UsageCredit, len(credits))
+		for _, credit := range credits {
+			creditByLog[credit.PomodoroUsageLogID] = credit
+		}
+
+		for _, usageLog := range chunk {
+			addUsageLog(usageLog, creditByLog[usageLog.ID.Hex()])
+		}
+		chunk = chunk[:0]
+		return nil
+	}
+
+	err = s.pomodoroUsageLogRepo.ForEach(ctx, func(usageLog *model.PomodoroUsageLog) error {
+		result.UsageLogs++
+		chunk = append(chunk, usageLog)
+		if len(chunk) < rebuildChunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to read usage logs: %w", err)
+	}
+
+	result.Boards = len(boards)
+	if dryRun {
+		return result, nil
+	}
+
+	for key, b := range boards {
+		if err := s.leaderboardCache.ReplaceScores(ctx, key, b.period, b.scores); err != nil {
+			return result, fmt.Errorf("failed to replace board %s: %w", key, err)
+		}
+		logger.Debug("Rebuilt leaderboard", zap.String("key", key), zap.Int("members", len(b.scores)))
+	}
+	return result, nil
+}
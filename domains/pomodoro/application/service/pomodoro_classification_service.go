@@ -2,9 +2,13 @@ package usecase
 
 import (
 	"context"
+	"fmt"
 	"go.uber.org/zap"
+	"math"
+	"pomocore-data/infrastructure/mongoDB/model"
 	"pomocore-data/shared/common/logger"
 	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -14,10 +18,11 @@ import (
 	"pomocore-data/domains/message"
 	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
 	pomodoroUseCase "pomocore-data/domains/pomodoro/application/usecase"
+	pomodoroDomain "pomocore-data/domains/pomodoro/domain"
 )
 
 type PatternClassifier interface {
-	Classify(app, title, url string) (categoryID string, isLLMBased bool)
+	Classify(ctx context.Context, app, title, url string) (categoryID string, isLLMBased bool)
 }
 
 type ClassificationTask struct {
@@ -35,10 +40,16 @@ type PomodoroClassificationService struct {
 	patternClassifier      PatternClassifier
 	categorizedDataRepo    pomodoroPort.CategorizedDataRepositoryPort
 	pomodoroUsageLogRepo   pomodoroPort.PomodoroUsageLogRepositoryPort
+	deferredRepo           pomodoroPort.DeferredClassificationRepositoryPort
+	outboxRepo             pomodoroPort.SessionScoreOutboxRepositoryPort
+	usageCreditRepo        pomodoroPort.UsageCreditRepositoryPort
+	transaction            pomodoroPort.TransactionPort
 	categoryPatternUseCase categoryPatternUseCase.CategoryPatternUseCase
 	leaderboardCache       port.LeaderboardCachePort
+	bucketing              *domain.Bucketing
 	categoryToIdMap        map[string]primitive.ObjectID
 	workerPool             int
+	classifyTimeout        time.Duration
 	mu                     sync.RWMutex
 }
 
@@ -46,8 +57,15 @@ func NewPomodoroClassificationService(
 	patternClassifier PatternClassifier,
 	categorizedDataRepo pomodoroPort.CategorizedDataRepositoryPort,
 	pomodoroUsageLogRepo pomodoroPort.PomodoroUsageLogRepositoryPort,
+	deferredRepo pomodoroPort.DeferredClassificationRepositoryPort,
+	outboxRepo pomodoroPort.SessionScoreOutboxRepositoryPort,
+	usageCreditRepo pomodoroPort.UsageCreditRepositoryPort,
+	transaction pomodoroPort.TransactionPort,
 	categoryPatternUseCase categoryPatternUseCase.CategoryPatternUseCase,
 	leaderboardCache port.LeaderboardCachePort,
+	bucketing *domain.Bucketing,
+	workerPool int,
+	classifyTimeout time.Duration,
 ) pomodoroUseCase.ClassifyPomodoroUseCase {
 	ctx := context.Background()
 	categoryIdToCategoryMap, err := categoryPatternUseCase.GetCategoryToIdMap(ctx)
@@ -62,10 +80,16 @@ func NewPomodoroClassificationService(
 		patternClassifier:      patternClassifier,
 		categorizedDataRepo:    categorizedDataRepo,
 		pomodoroUsageLogRepo:   pomodoroUsageLogRepo,
+		deferredRepo:           deferredRepo,
+		outboxRepo:             outboxRepo,
+		usageCreditRepo:        usageCreditRepo,
+		transaction:            transaction,
 		categoryPatternUseCase: categoryPatternUseCase,
 		leaderboardCache:       leaderboardCache,
+		bucketing:              bucketing,
 		categoryToIdMap:        categoryIdToCategoryMap,
-		workerPool:             10,
+		workerPool:             workerPool,
+		classifyTimeout:        classifyTimeout,
 	}
 }
 
@@ -73,45 +97,103 @@ func NewPomodoroClassificationService(
 func (s *PomodoroClassificationService) Execute(
 	ctx context.Context,
 	pomodoroMsgs []*message.PomodoroPatternClassifyMessage,
-) ([]*domain.LeaderboardEntry, []*message.SessionScoreMessage, error) {
+) ([]*pomodoroDomain.SessionActivity, []*message.SessionScoreMessage, error) {
 	if len(pomodoroMsgs) == 0 {
 		return nil, nil, nil
 	}
 
-	// Classify messages
-	classificationResults := s.classifyBatch(pomodoroMsgs)
+	// Classify messages; anything unfinished at the batch deadline is deferred for retry
+	classifyCtx, cancel := context.WithTimeout(ctx, s.classifyTimeout)
+	classificationResults := s.classifyBatch(classifyCtx, pomodoroMsgs)
+	cancel()
+
+	// Decide how much of each reported duration may be credited
+	credits, newCredits, err := s.creditUsage(ctx, pomodoroMsgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolvedCategories, err := s.resolvedCategories(ctx, pomodoroMsgs, credits)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Prepare data for updates
 	usageLogToCategoryIDMap := make(map[string]primitive.ObjectID)
 	categorizedDataToCategoryIDMap := make(map[string]primitive.ObjectID)
 	leaderboardUpdates := make([]*domain.LeaderboardEntry, 0, len(pomodoroMsgs))
+	activities := make([]*pomodoroDomain.SessionActivity, 0, len(pomodoroMsgs))
 	sessionScoreMessages := make([]*message.SessionScoreMessage, 0)
+	deferredClassifications := make([]*model.DeferredClassification, 0)
 
 	for i, result := range classificationResults {
 		pomodoroMsg := pomodoroMsgs[i]
+		credit := credits[i]
+		duration := credit.Credited
+		day := s.bucketing.Day(pomodoroMsg.SessionDate, pomodoroMsg.Timestamp, pomodoroMsg.Timezone)
 
 		category := result.Category
+		deferClassification := category == ""
+		if credit.Category != "" {
+			// A redelivered usage log keeps the category it was first credited to, since that attempt may have
+			// stopped halfway; Uncategorized usage is queued again in case it stopped before queueing it
+			category = credit.Category
+			deferClassification = category == uncategorizedCategory
+		}
 		if category == "" {
-			category = "Uncategorized"
-			logger.Warn("Classification failed, using default category",
+			category = uncategorizedCategory
+		}
+		// Usage the deferred retry has resolved since keeps its resolved category in the stored data. The leaderboard
+		// and session activity stay Uncategorized, as the retry moves the score and the session summary applies it.
+		storedCategory := category
+		if resolved, ok := resolvedCategories[pomodoroMsg.PomodoroUsageLogID]; ok {
+			storedCategory = resolved
+			deferClassification = false
+		}
+		credit.Category = category
+		if credit.LeaderboardDay.IsZero() {
+			credit.LeaderboardDay = day
+		}
+
+		if deferClassification {
+			logger.Warn("Usage is uncategorized, deferring retry and using default category",
 				zap.String("app", pomodoroMsg.App),
 				zap.String("title", pomodoroMsg.Title),
 				zap.String("url", pomodoroMsg.URL))
+			if deferred := newDeferredClassification(pomodoroMsg, duration, day); deferred != nil {
+				deferredClassifications = append(deferredClassifications, deferred)
+			}
+		}
+
+		// Create leaderboard entry; the cache applies each usage log once, so a redelivered one is sent again safely
+		// with its stored credit in case the previous attempt never reached the leaderboard
+		if duration > 0 {
+			leaderboardEntry := domain.NewLeaderboardEntry(
+				pomodoroMsg.PomodoroUsageLogID,
+				pomodoroMsg.UserID,
+				category,
+				duration,
+				pomodoroMsg.Timestamp,
+				day,
+			)
+			leaderboardUpdates = append(leaderboardUpdates, leaderboardEntry)
 		}
 
-		// Create leaderboard entry
-		leaderboardEntry := domain.NewLeaderboardEntry(
+		activities = append(activities, pomodoroDomain.NewSessionActivity(
 			pomodoroMsg.UserID,
+			pomodoroMsg.SessionDate,
+			pomodoroMsg.Session,
+			pomodoroMsg.PomodoroUsageLogID,
+			pomodoroMsg.App,
 			category,
-			pomodoroMsg.Duration,
+			duration,
 			pomodoroMsg.Timestamp,
-		)
-		leaderboardUpdates = append(leaderboardUpdates, leaderboardEntry)
+			result.IsLLM,
+		))
 
 		// Map category to ObjectID
-		categoryID := s.getCategoryID(category)
+		categoryID := s.getCategoryID(storedCategory)
 		if categoryID.IsZero() {
-			logger.Warn("No ObjectID found for category, using zero ObjectID", zap.String("category", category))
+			logger.Warn("No ObjectID found for category, using zero ObjectID", zap.String("category", storedCategory))
 		}
 		usageLogToCategoryIDMap[pomodoroMsg.PomodoroUsageLogID] = categoryID
 		categorizedDataToCategoryIDMap[pomodoroMsg.CategorizedDataID] = categoryID
@@ -126,28 +208,45 @@ func (s *PomodoroClassificationService) Execute(
 		}
 	}
 
-	// Update repositories
-	if err := s.pomodoroUsageLogRepo.UpdateCategoryIDsBatch(ctx, usageLogToCategoryIDMap); err != nil {
-		logger.Error("Error updating usageLog data", logger.WithError(err))
-		// Continue processing despite error
-	}
-
-	if err := s.categorizedDataRepo.UpdateCategoryIDsBatch(ctx, categorizedDataToCategoryIDMap); err != nil {
-		logger.Error("Error updating categorized data", logger.WithError(err))
-		// Continue processing despite error
+	// Update repositories and the session score outbox together so events never diverge from stored data.
+	// Without a transaction the writes may stop halfway. Credits go first and fix each usage log's duration and
+	// category, and every write is an upsert keyed by usage log or session, so a redelivery repeats them unchanged.
+	err = s.transaction.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.usageCreditRepo.SaveBatch(txCtx, newCredits); err != nil {
+			return fmt.Errorf("failed to save usage credits: %w", err)
+		}
+		if err := s.pomodoroUsageLogRepo.UpdateCategoryIDsBatch(txCtx, usageLogToCategoryIDMap); err != nil {
+			return fmt.Errorf("failed to update usage logs: %w", err)
+		}
+		if err := s.categorizedDataRepo.UpdateCategoryIDsBatch(txCtx, categorizedDataToCategoryIDMap); err != nil {
+			return fmt.Errorf("failed to update categorized data: %w", err)
+		}
+		// Queue failed classifications for retry
+		if err := s.deferredRepo.SaveBatch(txCtx, deferredClassifications); err != nil {
+			return fmt.Errorf("failed to save deferred classifications: %w", err)
+		}
+		if err := s.outboxRepo.RecordActivities(txCtx, activities); err != nil {
+			return fmt.Errorf("failed to record session activities: %w", err)
+		}
+		if err := s.outboxRepo.MarkEnded(txCtx, sessionScoreMessages); err != nil {
+			return fmt.Errorf("failed to mark sessions ended: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to persist classification results: %w", err)
 	}
 
-	// Update leaderboard cache
+	// Update leaderboard cache; on failure the batch is redelivered and entries already applied are skipped
 	if err := s.leaderboardCache.BatchIncreaseScore(ctx, leaderboardUpdates); err != nil {
-		logger.Error("Error increasing score", logger.WithError(err))
-		// Continue processing despite error
+		return nil, nil, fmt.Errorf("failed to increase leaderboard scores: %w", err)
 	}
 
-	return leaderboardUpdates, sessionScoreMessages, nil
+	return activities, sessionScoreMessages, nil
 }
 
 // classifyBatch classifies a batch of pomodoro messages using parallel workers
-func (s *PomodoroClassificationService) classifyBatch(pomodoroMsgs []*message.PomodoroPatternClassifyMessage) []ClassificationResult {
+func (s *PomodoroClassificationService) classifyBatch(ctx context.Context, pomodoroMsgs []*message.PomodoroPatternClassifyMessage) []ClassificationResult {
 	jobs := make(chan ClassificationTask, len(pomodoroMsgs))
 	results := make(chan ClassificationResult, len(pomodoroMsgs))
 
@@ -155,8 +254,12 @@ func (s *PomodoroClassificationService) classifyBatch(pomodoroMsgs []*message.Po
 	for w := 0; w < s.workerPool; w++ {
 		go func() {
 			for task := range jobs {
+				if ctx.Err() != nil {
+					results <- ClassificationResult{Index: task.Index}
+					continue
+				}
 				category, isLLM := s.patternClassifier.Classify(
-					task.Msg.App, task.Msg.Title, task.Msg.URL)
+					ctx, task.Msg.App, task.Msg.Title, task.Msg.URL)
 				results <- ClassificationResult{
 					Index:    task.Index,
 					Category: category,
@@ -182,6 +285,158 @@ func (s *PomodoroClassificationService) classifyBatch(pomodoroMsgs []*message.Po
 	return resultSlice
 }
 
+// creditUsage runs every message through its user's usage ledger, seeded with usage already credited around the
+// batch and in the batch's sessions, so overlaps and session totals also count usage credited by earlier batches.
+// It returns the credit per message, stored ones for messages credited before, and the new credits to store.
+func (s *PomodoroClassificationService) creditUsage(
+	ctx context.Context,
+	pomodoroMsgs []*message.PomodoroPatternClassifyMessage,
+) ([]*pomodoroDomain.UsageCredit, []*pomodoroDomain.UsageCredit, error) {
+	windowByUser := make(map[string]*pomodoroPort.UsageCreditWindow)
+	windows := make([]pomodoroPort.UsageCreditWindow, 0)
+	seenSessions := make(map[pomodoroPort.UsageCreditSession]bool)
+	sessions := make([]pomodoroPort.UsageCreditSession, 0)
+	for _, msg := range pomodoroMsgs {
+		window, ok := windowByUser[msg.UserID]
+		if !ok {
+			window = &pomodoroPort.UsageCreditWindow{UserID: msg.UserID, From: msg.Timestamp, To: msg.Timestamp}
+			windowByUser[msg.UserID] = window
+		}
+		window.From = math.Min(window.From, msg.Timestamp)
+		window.To = math.Max(window.To, msg.Timestamp+msg.Duration)
+
+		session := pomodoroPort.UsageCreditSession{UserID: msg.UserID, SessionDate: msg.SessionDate.UTC(), Session: msg.Session}
+		if !seenSessions[session] {
+			seenSessions[session] = true
+			sessions = append(sessions, session)
+		}
+	}
+	for _, window := range windowByUser {
+		windows = append(windows, *window)
+	}
+
+	// Without the stored credits a redelivered usage log could be credited differently
+	inWindows, err := s.usageCreditRepo.FindInWindows(ctx, windows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load credited usage: %w", err)
+	}
+	inSessions, err := s.usageCreditRepo.FindBySessions(ctx, sessions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load credited session usage: %w", err)
+	}
+
+	existingByUser := make(map[string][]*pomodoroDomain.UsageCredit)
+	loaded := make(map[string]bool, len(inWindows)+len(inSessions))
+	for _, credit := range append(inWindows, inSessions...) {
+		if loaded[credit.PomodoroUsageLogID] {
+			continue
+		}
+		loaded[credit.PomodoroUsageLogID] = true
+		existingByUser[credit.UserID] = append(existingByUser[credit.UserID], credit)
+	}
+
+	ledgers := make(map[string]*pomodoroDomain.UsageLedger)
+	credits := make([]*pomodoroDomain.UsageCredit, len(pomodoroMsgs))
+	newCredits := make([]*pomodoroDomain.UsageCredit, 0, len(pomodoroMsgs))
+	for i, msg := range pomodoroMsgs {
+		ledger, ok := ledgers[msg.UserID]
+		if !ok {
+			ledger = pomodoroDomain.NewUsageLedger(existingByUser[msg.UserID])
+			ledgers[msg.UserID] = ledger
+		}
+
+		credit, replayed := ledger.Credit(&pomodoroDomain.UsageCredit{
+			UserID:             msg.UserID,
+			PomodoroUsageLogID: msg.PomodoroUsageLogID,
+			SessionDate:        msg.SessionDate,
+			Session:            msg.Session,
+			SessionMinutes:     msg.SessionMinutes,
+			Start:              msg.Timestamp,
+			Duration:           msg.Duration,
+		})
+		credits[i] = credit
+		if replayed {
+			continue
+		}
+		newCredits = append(newCredits, credit)
+
+		if credit.IsRejected() {
+			logger.Warn("Rejected part of reported usage duration",
+				zap.String("user_id", msg.UserID),
+				zap.String("usage_log_id", msg.PomodoroUsageLogID),
+				zap.Int("session", msg.Session),
+				zap.Int("session_minutes", msg.SessionMinutes),
+				zap.Float64("duration", msg.Duration),
+				zap.Float64("credited", credit.Credited),
+				zap.Float64("rejected", credit.Rejected),
+				zap.Strings("reasons", credit.Reasons))
+		}
+	}
+
+	return credits, newCredits, nil
+}
+
+// resolvedCategories returns the categories the deferred retry resolved for redelivered Uncategorized usage logs,
+// keyed by usage log, so a redelivery does not write Uncategorized back over them
+func (s *PomodoroClassificationService) resolvedCategories(
+	ctx context.Context,
+	pomodoroMsgs []*message.PomodoroPatternClassifyMessage,
+	credits []*pomodoroDomain.UsageCredit,
+) (map[string]string, error) {
+	resolvedCategories := make(map[string]string)
+	seenSessions := make(map[pomodoroPort.UsageCreditSession]bool)
+	for i, msg := range pomodoroMsgs {
+		if credits[i].Category != uncategorizedCategory {
+			continue
+		}
+		session := pomodoroPort.UsageCreditSession{UserID: msg.UserID, SessionDate: msg.SessionDate.UTC(), Session: msg.Session}
+		if seenSessions[session] {
+			continue
+		}
+		seenSessions[session] = true
+
+		resolved, err := s.deferredRepo.FindResolvedBySession(ctx, msg.UserID, msg.SessionDate, msg.Session)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load resolved deferred classifications: %w", err)
+		}
+		for _, item := range resolved {
+			resolvedCategories[item.PomodoroUsageLogID.Hex()] = item.Category
+		}
+	}
+	return resolvedCategories, nil
+}
+
+// newDeferredClassification builds a retry item for a message, or nil if its IDs cannot be resolved later
+func newDeferredClassification(pomodoroMsg *message.PomodoroPatternClassifyMessage, duration float64, leaderboardDay time.Time) *model.DeferredClassification {
+	usageLogID, err := primitive.ObjectIDFromHex(pomodoroMsg.PomodoroUsageLogID)
+	if err != nil {
+		logger.Warn("Cannot defer classification with invalid usage log ID",
+			zap.String("usage_log_id", pomodoroMsg.PomodoroUsageLogID))
+		return nil
+	}
+	categorizedDataID, err := primitive.ObjectIDFromHex(pomodoroMsg.CategorizedDataID)
+	if err != nil {
+		logger.Warn("Cannot defer classification with invalid categorized data ID",
+			zap.String("categorized_data_id", pomodoroMsg.CategorizedDataID))
+		return nil
+	}
+
+	return model.NewDeferredClassification(
+		pomodoroMsg.UserID,
+		usageLogID,
+		categorizedDataID,
+		pomodoroMsg.App,
+		pomodoroMsg.Title,
+		pomodoroMsg.URL,
+		pomodoroMsg.Session,
+		pomodoroMsg.SessionDate,
+		duration,
+		pomodoroMsg.Timestamp,
+		leaderboardDay,
+		time.Now(),
+	)
+}
+
 // getCategoryID returns the ObjectID for a given category name
 func (s *PomodoroClassificationService) getCategoryID(category string) primitive.ObjectID {
 	s.mu.RLock()
@@ -0,0 +1,286 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	categoryPatternUseCase "pomocore-data/domains/categoryPattern/application/useCase"
+	"pomocore-data/domains/leaderboard/application/port"
+	"pomocore-data/domains/leaderboard/domain"
+	"pomocore-data/domains/message"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	pomodoroDomain "pomocore-data/domains/pomodoro/domain"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"pomocore-data/shared/common/logger"
+)
+
+// The fakes embed their port so only the methods the classification service calls need an implementation
+
+type fixedClassifier struct{ category string }
+
+func (c fixedClassifier) Classify(ctx context.Context, app, title, url string) (string, bool) {
+	return c.category, false
+}
+
+type fakeUsageCreditRepo struct {
+	pomodoroPort.UsageCreditRepositoryPort
+	credits map[string]*pomodoroDomain.UsageCredit
+}
+
+func (r *fakeUsageCreditRepo) FindInWindows(ctx context.Context, windows []pomodoroPort.UsageCreditWindow) ([]*pomodoroDomain.UsageCredit, error) {
+	var found []*pomodoroDomain.UsageCredit
+	for _, credit := range r.credits {
+		for _, window := range windows {
+			if credit.UserID == window.UserID && credit.Start <= window.To && credit.End() >= window.From {
+				found = append(found, r.copy(credit))
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeUsageCreditRepo) FindBySessions(ctx context.Context, sessions []pomodoroPort.UsageCreditSession) ([]*pomodoroDomain.UsageCredit, error) {
+	var found []*pomodoroDomain.UsageCredit
+	for _, credit := range r.credits {
+		for _, session := range sessions {
+			if credit.UserID == session.UserID && credit.SessionDate.Equal(session.SessionDate) && credit.Session == session.Session {
+				found = append(found, r.copy(credit))
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeUsageCreditRepo) SaveBatch(ctx context.Context, credits []*pomodoroDomain.UsageCredit) error {
+	for _, credit := range credits {
+		if _, ok := r.credits[credit.PomodoroUsageLogID]; !ok {
+			r.credits[credit.PomodoroUsageLogID] = r.copy(credit)
+		}
+	}
+	return nil
+}
+
+// copy returns a credit as a fresh read from the store would
+func (r *fakeUsageCreditRepo) copy(credit *pomodoroDomain.UsageCredit) *pomodoroDomain.UsageCredit {
+	c := *credit
+	return &c
+}
+
+type fakeUsageLogRepo struct {
+	pomodoroPort.PomodoroUsageLogRepositoryPort
+	categoryIDs map[string]primitive.ObjectID
+}
+
+func (r *fakeUsageLogRepo) UpdateCategoryIDsBatch(ctx context.Context, usageLogToCategoryIDMap map[string]primitive.ObjectID) error {
+	for id, categoryID := range usageLogToCategoryIDMap {
+		r.categoryIDs[id] = categoryID
+	}
+	return nil
+}
+
+type fakeCategorizedDataRepo struct {
+	pomodoroPort.CategorizedDataRepositoryPort
+	categoryIDs map[string]primitive.ObjectID
+}
+
+func (r *fakeCategorizedDataRepo) UpdateCategoryIDsBatch(ctx context.Context, categorizedDataToCategoryIDMap map[string]primitive.ObjectID) error {
+	for id, categoryID := range categorizedDataToCategoryIDMap {
+		r.categoryIDs[id] = categoryID
+	}
+	return nil
+}
+
+type fakeDeferredRepo struct {
+	pomodoroPort.DeferredClassificationRepositoryPort
+	items map[primitive.ObjectID]*model.DeferredClassification
+}
+
+// SaveBatch upserts on the usage log like the Mongo adapter, leaving items already queued untouched
+func (r *fakeDeferredRepo) SaveBatch(ctx context.Context, items []*model.DeferredClassification) error {
+	for _, item := range items {
+		if _, ok := r.items[item.PomodoroUsageLogID]; !ok {
+			r.items[item.PomodoroUsageLogID] = item
+		}
+	}
+	return nil
+}
+
+func (r *fakeDeferredRepo) FindResolvedBySession(ctx context.Context, userID string, sessionDate time.Time, session int) ([]*model.DeferredClassification, error) {
+	var found []*model.DeferredClassification
+	for _, item := range r.items {
+		if item.UserID == userID && item.SessionDate.Equal(sessionDate) && item.Session == session && item.Status == model.DeferredStatusResolved {
+			found = append(found, item)
+		}
+	}
+	return found, nil
+}
+
+type fakeOutboxRepo struct {
+	pomodoroPort.SessionScoreOutboxRepositoryPort
+}
+
+func (r *fakeOutboxRepo) RecordActivities(ctx context.Context, activities []*pomodoroDomain.SessionActivity) error {
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkEnded(ctx context.Context, sessionScoreMsgs []*message.SessionScoreMessage) error {
+	return nil
+}
+
+type noTransaction struct{}
+
+func (noTransaction) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeCategoryPatterns struct {
+	categoryPatternUseCase.CategoryPatternUseCase
+	ids map[string]primitive.ObjectID
+}
+
+func (c fakeCategoryPatterns) GetCategoryToIdMap(ctx context.Context) (map[string]primitive.ObjectID, error) {
+	return c.ids, nil
+}
+
+type fakeLeaderboardCache struct {
+	port.LeaderboardCachePort
+	applied map[string]bool
+	scores  map[string]float64
+}
+
+// BatchIncreaseScore applies each entry ID once like the Redis script and sums scores per user and category
+func (c *fakeLeaderboardCache) BatchIncreaseScore(ctx context.Context, entries []*domain.LeaderboardEntry) error {
+	for _, entry := range entries {
+		if entry.EntryID != "" {
+			if c.applied[entry.EntryID] {
+				continue
+			}
+			c.applied[entry.EntryID] = true
+		}
+		c.scores[entry.UserID+"/"+entry.Category] += entry.Duration
+	}
+	return nil
+}
+
+type classificationFixture struct {
+	service         *PomodoroClassificationService
+	credits         *fakeUsageCreditRepo
+	usageLogs       *fakeUsageLogRepo
+	categorizedData *fakeCategorizedDataRepo
+	deferred        *fakeDeferredRepo
+	leaderboard     *fakeLeaderboardCache
+	categoryIDs     map[string]primitive.ObjectID
+}
+
+func newClassificationFixture(t *testing.T, category string) *classificationFixture {
+	t.Helper()
+	logger.Logger = zap.NewNop()
+
+	f := &classificationFixture{
+		credits:         &fakeUsageCreditRepo{credits: make(map[string]*pomodoroDomain.UsageCredit)},
+		usageLogs:       &fakeUsageLogRepo{categoryIDs: make(map[string]primitive.ObjectID)},
+		categorizedData: &fakeCategorizedDataRepo{categoryIDs: make(map[string]primitive.ObjectID)},
+		deferred:        &fakeDeferredRepo{items: make(map[primitive.ObjectID]*model.DeferredClassification)},
+		leaderboard:     &fakeLeaderboardCache{applied: make(map[string]bool), scores: make(map[string]float64)},
+		categoryIDs: map[string]primitive.ObjectID{
+			"Development":         primitive.NewObjectID(),
+			uncategorizedCategory: primitive.NewObjectID(),
+		},
+	}
+	f.service = NewPomodoroClassificationService(
+		fixedClassifier{category: category},
+		f.categorizedData,
+		f.usageLogs,
+		f.deferred,
+		&fakeOutboxRepo{},
+		f.credits,
+		noTransaction{},
+		fakeCategoryPatterns{ids: f.categoryIDs},
+		f.leaderboard,
+		domain.NewBucketing(time.UTC, domain.DaySourceSessionDate),
+		2,
+		time.Minute,
+	).(*PomodoroClassificationService)
+	return f
+}
+
+var testSessionDate = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// usageMessage is a usage log of the user's first session on testSessionDate, starting offset seconds into it
+func usageMessage(n int, offset, duration float64) *message.PomodoroPatternClassifyMessage {
+	return &message.PomodoroPatternClassifyMessage{
+		UserID:             "user",
+		CategorizedDataID:  fmt.Sprintf("%024x", n),
+		PomodoroUsageLogID: fmt.Sprintf("%024x", 1000+n),
+		App:                "Code",
+		Session:            1,
+		SessionDate:        testSessionDate,
+		SessionMinutes:     25,
+		Duration:           duration,
+		Timestamp:          float64(testSessionDate.Unix()) + offset,
+	}
+}
+
+func TestSessionTotalIsCappedAcrossBatches(t *testing.T) {
+	f := newClassificationFixture(t, "Development")
+	ctx := context.Background()
+
+	// 1400s of the 1500s session arrive first; the second batch's window does not overlap the first one's usage
+	batches := [][]*message.PomodoroPatternClassifyMessage{
+		{usageMessage(1, 0, 1000), usageMessage(2, 1000, 400)},
+		{usageMessage(3, 3000, 600)},
+	}
+	for i, batch := range batches {
+		if _, _, err := f.service.Execute(ctx, batch); err != nil {
+			t.Fatalf("batch %d: Execute: %v", i+1, err)
+		}
+	}
+
+	if got := f.leaderboard.scores["user/Development"]; got != 1500 {
+		t.Errorf("credited %v, want the session length of 1500", got)
+	}
+	last := f.credits.credits[usageMessage(3, 0, 0).PomodoroUsageLogID]
+	if last.Credited != 100 || len(last.Reasons) != 1 || last.Reasons[0] != pomodoroDomain.RejectReasonSessionTotal {
+		t.Errorf("last credit = %v with reasons %v, want 100 capped by %s", last.Credited, last.Reasons, pomodoroDomain.RejectReasonSessionTotal)
+	}
+}
+
+func TestReplayKeepsCategoryResolvedByDeferredRetry(t *testing.T) {
+	f := newClassificationFixture(t, "")
+	ctx := context.Background()
+	batch := []*message.PomodoroPatternClassifyMessage{usageMessage(1, 0, 600)}
+
+	if _, _, err := f.service.Execute(ctx, batch); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(f.deferred.items) != 1 {
+		t.Fatalf("deferred %d items, want 1", len(f.deferred.items))
+	}
+	// The deferred retry resolves the usage before the batch is redelivered
+	for _, item := range f.deferred.items {
+		item.Status = model.DeferredStatusResolved
+		item.Category = "Development"
+	}
+
+	if _, _, err := f.service.Execute(ctx, batch); err != nil {
+		t.Fatalf("replay: Execute: %v", err)
+	}
+
+	want := f.categoryIDs["Development"]
+	if got := f.usageLogs.categoryIDs[batch[0].PomodoroUsageLogID]; got != want {
+		t.Errorf("usage log category = %s, want the resolved %s", got.Hex(), want.Hex())
+	}
+	if got := f.categorizedData.categoryIDs[batch[0].CategorizedDataID]; got != want {
+		t.Errorf("categorized data category = %s, want the resolved %s", got.Hex(), want.Hex())
+	}
+	if got := f.leaderboard.scores["user/"+uncategorizedCategory]; got != 600 {
+		t.Errorf("uncategorized score = %v, want 600 applied once", got)
+	}
+}
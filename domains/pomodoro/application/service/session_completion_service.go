@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/domains/message"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	pomodoroUseCase "pomocore-data/domains/pomodoro/application/usecase"
+	pomodoroDomain "pomocore-data/domains/pomodoro/domain"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+)
+
+// SessionCompletionService finalizes session score events stored in the outbox once their sessions are
+// fully categorized and relays them to the stream with at-least-once delivery
+type SessionCompletionService struct {
+	pomodoroUsageLogRepo pomodoroPort.PomodoroUsageLogRepositoryPort
+	deferredRepo         pomodoroPort.DeferredClassificationRepositoryPort
+	outboxRepo           pomodoroPort.SessionScoreOutboxRepositoryPort
+	sessionScoreEvents   pomodoroPort.SessionScoreEventPort
+	completionTimeout    time.Duration
+	publishLease         time.Duration
+	maxPublishAttempts   int
+	batchSize            int
+}
+
+func NewSessionCompletionService(
+	pomodoroUsageLogRepo pomodoroPort.PomodoroUsageLogRepositoryPort,
+	deferredRepo pomodoroPort.DeferredClassificationRepositoryPort,
+	outboxRepo pomodoroPort.SessionScoreOutboxRepositoryPort,
+	sessionScoreEvents pomodoroPort.SessionScoreEventPort,
+	completionTimeout time.Duration,
+) pomodoroUseCase.SessionCompletionUseCase {
+	return &SessionCompletionService{
+		pomodoroUsageLogRepo: pomodoroUsageLogRepo,
+		deferredRepo:         deferredRepo,
+		outboxRepo:           outboxRepo,
+		sessionScoreEvents:   sessionScoreEvents,
+		completionTimeout:    completionTimeout,
+		publishLease:         30 * time.Second,
+		maxPublishAttempts:   10,
+		batchSize:            100,
+	}
+}
+
+func (s *SessionCompletionService) CompleteSessions(ctx context.Context, sessionScoreMsgs []*message.SessionScoreMessage) error {
+	if len(sessionScoreMsgs) == 0 {
+		return nil
+	}
+
+	eventIDs := make([]string, 0, len(sessionScoreMsgs))
+	for _, msg := range sessionScoreMsgs {
+		eventIDs = append(eventIDs, msg.EventID)
+	}
+
+	rows, err := s.outboxRepo.FindAwaitingByEventIDs(ctx, eventIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load awaiting sessions: %w", err)
+	}
+
+	for _, row := range rows {
+		if _, err := s.finalize(ctx, row); err != nil {
+			logger.Error("Error checking session completion",
+				zap.String("event_id", row.EventID),
+				logger.WithError(err))
+		}
+	}
+
+	_, err = s.publishReady(ctx)
+	return err
+}
+
+func (s *SessionCompletionService) RelayPending(ctx context.Context) (int, error) {
+	rows, err := s.outboxRepo.FindAwaiting(ctx, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load awaiting sessions: %w", err)
+	}
+
+	for _, row := range rows {
+		if _, err := s.finalize(ctx, row); err != nil {
+			logger.Error("Error checking session completion",
+				zap.String("event_id", row.EventID),
+				logger.WithError(err))
+		}
+	}
+
+	return s.publishReady(ctx)
+}
+
+// finalize builds the session score event once the session is complete or has timed out and reports whether it did
+func (s *SessionCompletionService) finalize(ctx context.Context, row *model.SessionScoreOutbox) (bool, error) {
+	outstanding, err := s.countOutstanding(ctx, row)
+	if err != nil {
+		return false, err
+	}
+
+	msg := message.NewSessionScoreMessage(row.UserID, row.SessionDate, row.Session)
+	if outstanding > 0 {
+		if time.Now().Before(row.EndedAt.Add(s.completionTimeout)) {
+			return false, nil
+		}
+		msg.MarkPartial()
+		logger.Warn("Session completion timed out, publishing partial session score event",
+			zap.String("event_id", row.EventID),
+			zap.Int64("outstanding", outstanding))
+	}
+
+	if err := s.applySummary(ctx, row, msg); err != nil {
+		return false, err
+	}
+
+	if err := s.outboxRepo.MarkReady(ctx, row.ID, msg); err != nil {
+		return false, fmt.Errorf("failed to mark session ready: %w", err)
+	}
+	return true, nil
+}
+
+// publishReady relays ready outbox rows; a crash between publishing and marking sent causes a redelivery,
+// which consumers dedupe by event ID. Rows that failed maxPublishAttempts times are parked as failed.
+func (s *SessionCompletionService) publishReady(ctx context.Context) (int, error) {
+	now := time.Now()
+	parked, err := s.outboxRepo.ParkExhausted(ctx, now, s.maxPublishAttempts)
+	if err != nil {
+		logger.Error("Error parking exhausted session score events", logger.WithError(err))
+	}
+	if parked > 0 {
+		metrics.OutboxParked.Add(float64(parked))
+		logger.Error("Gave up publishing session score events",
+			zap.Int64("parked", parked),
+			zap.Int("max_attempts", s.maxPublishAttempts))
+	}
+
+	rows, err := s.outboxRepo.ClaimReady(ctx, now, s.publishLease, s.maxPublishAttempts, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim ready session events: %w", err)
+	}
+
+	published := 0
+	for _, row := range rows {
+		if row.Payload == nil {
+			continue
+		}
+
+		if err := s.sessionScoreEvents.Publish(ctx, row.Payload); err != nil {
+			logger.Error("Error sending sessionScore message",
+				zap.String("event_id", row.EventID),
+				logger.WithError(err))
+			if err := s.outboxRepo.MarkPublishFailed(ctx, row.ID, err.Error()); err != nil {
+				logger.Error("Error recording outbox publish failure", logger.WithError(err))
+			}
+			continue
+		}
+
+		if err := s.outboxRepo.MarkSent(ctx, row.ID); err != nil {
+			logger.Error("Error marking outbox row sent",
+				zap.String("event_id", row.EventID),
+				logger.WithError(err))
+			continue
+		}
+		published++
+
+		logger.Info("Classification Commited",
+			zap.String("user_id", row.Payload.UserID),
+			zap.String("session_date", row.Payload.SessionDate.Format("2006-01-02")),
+			zap.Int("session", row.Payload.Session),
+			zap.Bool("partial", row.Payload.IsPartial))
+	}
+	return published, nil
+}
+
+// applySummary fills the category breakdown, taking categories resolved by the deferred retry into account
+func (s *SessionCompletionService) applySummary(ctx context.Context, row *model.SessionScoreOutbox, msg *message.SessionScoreMessage) error {
+	resolved, err := s.deferredRepo.FindResolvedBySession(ctx, row.UserID, row.SessionDate, row.Session)
+	if err != nil {
+		return fmt.Errorf("failed to load resolved deferred classifications: %w", err)
+	}
+	resolvedCategories := make(map[string]string, len(resolved))
+	for _, item := range resolved {
+		resolvedCategories[item.PomodoroUsageLogID.Hex()] = item.Category
+	}
+
+	activities := make([]*pomodoroDomain.SessionActivity, 0, len(row.Activities))
+	for usageLogID, stored := range row.Activities {
+		activity := pomodoroDomain.NewSessionActivity(
+			row.UserID,
+			row.SessionDate,
+			row.Session,
+			usageLogID,
+			stored.App,
+			stored.Category,
+			stored.Duration,
+			stored.Timestamp,
+			stored.IsLLMBased,
+		)
+		if category, ok := resolvedCategories[usageLogID]; ok {
+			activity.Category = category
+			activity.IsLLMBased = true
+		}
+		activities = append(activities, activity)
+	}
+
+	summary := pomodoroDomain.SummarizeSession(activities)
+	msg.CategorySeconds = summary.CategorySeconds
+	msg.WorkSeconds = summary.WorkSeconds
+	msg.NonWorkSeconds = summary.NonWorkSeconds
+	msg.DistinctApps = summary.DistinctApps
+	msg.ContextSwitches = summary.ContextSwitches
+	msg.LLMClassifiedShare = summary.LLMClassifiedShare
+	return nil
+}
+
+func (s *SessionCompletionService) countOutstanding(ctx context.Context, row *model.SessionScoreOutbox) (int64, error) {
+	uncategorized, err := s.pomodoroUsageLogRepo.CountUncategorizedBySession(ctx, row.UserID, row.SessionDate, row.Session)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count uncategorized usage logs: %w", err)
+	}
+
+	deferred, err := s.deferredRepo.CountPendingBySession(ctx, row.UserID, row.SessionDate, row.Session)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deferred classifications: %w", err)
+	}
+
+	return uncategorized + deferred, nil
+}
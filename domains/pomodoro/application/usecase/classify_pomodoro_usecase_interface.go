@@ -3,15 +3,15 @@ package usecase
 import (
 	"context"
 
-	"pomocore-data/domains/leaderboard/domain"
 	"pomocore-data/domains/message"
+	"pomocore-data/domains/pomodoro/domain"
 )
 
 type ClassifyPomodoroUseCase interface {
 	Execute(
 		ctx context.Context,
 		pomodoroMsgs []*message.PomodoroPatternClassifyMessage,
-	) ([]*domain.LeaderboardEntry, []*message.SessionScoreMessage, error)
-	
+	) ([]*domain.SessionActivity, []*message.SessionScoreMessage, error)
+
 	RefreshCategoryMapping(ctx context.Context) error
-}
\ No newline at end of file
+}
@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"context"
+
+	"pomocore-data/domains/leaderboard/domain"
+)
+
+type RebuildLeaderboardResult struct {
+	UsageLogs int
+	// StoredCredits counts the usage logs rebuilt from their stored credit rather than recomputed
+	StoredCredits int
+	Boards        int
+}
+
+type RebuildLeaderboardUseCase interface {
+	// Rebuild recomputes the boards of the given periods from every stored usage log and its stored credit,
+	// and replaces them. Consumers must be stopped while it runs. With dryRun the totals are computed but nothing is written.
+	Rebuild(ctx context.Context, periods []domain.Period, dryRun bool) (RebuildLeaderboardResult, error)
+}
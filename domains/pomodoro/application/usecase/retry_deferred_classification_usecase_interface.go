@@ -0,0 +1,10 @@
+package usecase
+
+import (
+	"context"
+)
+
+type RetryDeferredClassificationUseCase interface {
+	// RetryDue retries classification for deferred items whose retry time has passed and returns how many were resolved
+	RetryDue(ctx context.Context) (int, error)
+}
@@ -0,0 +1,16 @@
+package usecase
+
+import (
+	"context"
+
+	"pomocore-data/domains/message"
+)
+
+type SessionCompletionUseCase interface {
+	// CompleteSessions finalizes the given ended sessions right away if every usage log is categorized,
+	// instead of waiting for the next relay run
+	CompleteSessions(ctx context.Context, sessionScoreMsgs []*message.SessionScoreMessage) error
+
+	// RelayPending finalizes waiting sessions, as partial once timed out, and publishes every ready outbox row
+	RelayPending(ctx context.Context) (int, error)
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	leaderboardDomain "pomocore-data/domains/leaderboard/domain"
+)
+
+// SessionActivity is the classification outcome of a single usage log within a pomodoro session
+type SessionActivity struct {
+	UserID             string
+	SessionDate        time.Time
+	Session            int
+	PomodoroUsageLogID string
+	App                string
+	Category           string
+	Duration           float64
+	Timestamp          float64
+	IsLLMBased         bool
+}
+
+func NewSessionActivity(
+	userID string,
+	sessionDate time.Time,
+	session int,
+	pomodoroUsageLogID string,
+	app string,
+	category string,
+	duration float64,
+	timestamp float64,
+	isLLMBased bool,
+) *SessionActivity {
+	return &SessionActivity{
+		UserID:             userID,
+		SessionDate:        sessionDate,
+		Session:            session,
+		PomodoroUsageLogID: pomodoroUsageLogID,
+		App:                app,
+		Category:           category,
+		Duration:           duration,
+		Timestamp:          timestamp,
+		IsLLMBased:         isLLMBased,
+	}
+}
+
+// SessionSummary is the per-session breakdown sent with the session score event
+type SessionSummary struct {
+	CategorySeconds    map[string]float64
+	WorkSeconds        float64
+	NonWorkSeconds     float64
+	DistinctApps       int
+	ContextSwitches    int
+	LLMClassifiedShare float64
+}
+
+// SummarizeSession aggregates activities in timestamp order; a context switch is a change of category between consecutive activities
+func SummarizeSession(activities []*SessionActivity) SessionSummary {
+	sorted := make([]*SessionActivity, len(activities))
+	copy(sorted, activities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+
+	summary := SessionSummary{CategorySeconds: make(map[string]float64)}
+	apps := make(map[string]bool)
+	llmSeconds := 0.0
+	previousCategory := ""
+
+	for _, activity := range sorted {
+		summary.CategorySeconds[activity.Category] += activity.Duration
+		if leaderboardDomain.IsWorkCategory(activity.Category) {
+			summary.WorkSeconds += activity.Duration
+		} else {
+			summary.NonWorkSeconds += activity.Duration
+		}
+
+		if activity.App != "" {
+			apps[strings.ToLower(activity.App)] = true
+		}
+		if activity.IsLLMBased {
+			llmSeconds += activity.Duration
+		}
+
+		if previousCategory != "" && activity.Category != previousCategory {
+			summary.ContextSwitches++
+		}
+		previousCategory = activity.Category
+	}
+
+	summary.DistinctApps = len(apps)
+	if total := summary.WorkSeconds + summary.NonWorkSeconds; total > 0 {
+		summary.LLMClassifiedShare = llmSeconds / total
+	}
+	return summary
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	RejectReasonSessionLength = "exceeds_session_length"
+	RejectReasonOverlap       = "overlapping_usage"
+	RejectReasonSessionTotal  = "session_total_exceeded"
+)
+
+// Overlaps up to this many seconds are treated as timestamp rounding, not double counting
+const overlapTolerance = 1.0
+
+// UsageCredit is the part of a usage log's reported duration that is credited to the leaderboards
+type UsageCredit struct {
+	UserID             string
+	PomodoroUsageLogID string
+	SessionDate        time.Time
+	Session            int
+	SessionMinutes     int
+	Start              float64
+	Duration           float64
+	Credited           float64
+	Rejected           float64
+	Reasons            []string
+	// Category is the leaderboard category the usage was credited to; a redelivered usage log keeps it
+	Category string
+	// LeaderboardDay is the day the usage was credited to, resolved in the user's timezone
+	LeaderboardDay time.Time
+}
+
+// End is where the reported usage ends. Overlap is judged on the reported interval rather than the credited
+// amount, since the credited part has no position of its own within it.
+func (c *UsageCredit) End() float64 {
+	return c.Start + math.Max(c.Duration, 0)
+}
+
+func (c *UsageCredit) IsRejected() bool {
+	return c.Rejected > 0
+}
+
+func (c *UsageCredit) sameSession(other *UsageCredit) bool {
+	return c.SessionDate.Equal(other.SessionDate) && c.Session == other.Session
+}
+
+// UsageLedger holds the usage already credited to one user and decides how much of new usage can be credited
+type UsageLedger struct {
+	credits map[string]*UsageCredit
+}
+
+func NewUsageLedger(existing []*UsageCredit) *UsageLedger {
+	credits := make(map[string]*UsageCredit, len(existing))
+	for _, credit := range existing {
+		credits[credit.PomodoroUsageLogID] = credit
+	}
+	return &UsageLedger{credits: credits}
+}
+
+// Credit removes time overlapping previously reported usage, caps the rest by the session length and keeps
+// the session total within the session length. A usage log that was already credited returns its stored
+// credit with replayed set.
+func (l *UsageLedger) Credit(usage *UsageCredit) (credit *UsageCredit, replayed bool) {
+	if existing, ok := l.credits[usage.PomodoroUsageLogID]; ok {
+		return existing, true
+	}
+
+	credit = usage
+	credit.Credited = math.Max(usage.Duration, 0)
+	credit.Reasons = nil
+	sessionLimit := float64(usage.SessionMinutes * 60)
+
+	if overlap := l.covered(credit.Start, credit.End()); overlap > 0 {
+		credit.Credited = math.Max(credit.Credited-overlap, 0)
+		credit.Reasons = append(credit.Reasons, RejectReasonOverlap)
+	}
+
+	if sessionLimit > 0 && credit.Credited > sessionLimit {
+		credit.Credited = sessionLimit
+		credit.Reasons = append(credit.Reasons, RejectReasonSessionLength)
+	}
+
+	if sessionLimit > 0 {
+		used := 0.0
+		for _, other := range l.credits {
+			if other.sameSession(credit) {
+				used += other.Credited
+			}
+		}
+		if used+credit.Credited > sessionLimit {
+			credit.Credited = math.Max(sessionLimit-used, 0)
+			credit.Reasons = append(credit.Reasons, RejectReasonSessionTotal)
+		}
+	}
+
+	credit.Rejected = math.Max(usage.Duration, 0) - credit.Credited
+	l.credits[credit.PomodoroUsageLogID] = credit
+	return credit, false
+}
+
+// covered returns how much of [start, end] lies within usage reported before. Reported intervals are merged
+// first so time covered by several of them is counted once; overlaps within the tolerance are ignored.
+func (l *UsageLedger) covered(start, end float64) float64 {
+	intervals := make([][2]float64, 0, len(l.credits))
+	for _, other := range l.credits {
+		from, to := math.Max(start, other.Start), math.Min(end, other.End())
+		if to > from {
+			intervals = append(intervals, [2]float64{from, to})
+		}
+	}
+	slices.SortFunc(intervals, func(a, b [2]float64) int {
+		return cmp.Compare(a[0], b[0])
+	})
+
+	covered := 0.0
+	for i := 0; i < len(intervals); {
+		from, to := intervals[i][0], intervals[i][1]
+		for i++; i < len(intervals) && intervals[i][0] <= to; i++ {
+			to = math.Max(to, intervals[i][1])
+		}
+		if to-from > overlapTolerance {
+			covered += to - from
+		}
+	}
+	return covered
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUsageLedgerCredit(t *testing.T) {
+	sessionDate := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	usage := func(id string, session int, start, duration float64) *UsageCredit {
+		return &UsageCredit{
+			UserID:             "user",
+			PomodoroUsageLogID: id,
+			SessionDate:        sessionDate,
+			Session:            session,
+			SessionMinutes:     25,
+			Start:              start,
+			Duration:           duration,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		existing     []*UsageCredit
+		earlier      []*UsageCredit
+		usage        *UsageCredit
+		wantCredited float64
+		wantReasons  []string
+		wantReplayed bool
+	}{
+		{
+			name:         "no earlier usage",
+			usage:        usage("a", 1, 0, 100),
+			wantCredited: 100,
+		},
+		{
+			name:         "negative duration",
+			usage:        usage("a", 1, 0, -5),
+			wantCredited: 0,
+		},
+		{
+			name:         "capped by session length",
+			usage:        usage("a", 1, 0, 2000),
+			wantCredited: 1500,
+			wantReasons:  []string{RejectReasonSessionLength},
+		},
+		{
+			name:         "partial overlap",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 100)},
+			usage:        usage("b", 2, 50, 150),
+			wantCredited: 100,
+			wantReasons:  []string{RejectReasonOverlap},
+		},
+		{
+			name:         "duplicate timestamp",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 100)},
+			usage:        usage("b", 2, 0, 100),
+			wantCredited: 0,
+			wantReasons:  []string{RejectReasonOverlap},
+		},
+		{
+			name:         "overlap within tolerance",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 100)},
+			usage:        usage("b", 2, 99.5, 100),
+			wantCredited: 100,
+		},
+		{
+			// b was credited 150s of [50, 200]; the overlap check must still see all of it
+			name:         "overlap with the part of earlier usage beyond its credit",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 100), usage("b", 2, 50, 150)},
+			usage:        usage("c", 3, 160, 40),
+			wantCredited: 0,
+			wantReasons:  []string{RejectReasonOverlap},
+		},
+		{
+			name:         "time covered by several earlier usages counts once",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 100), usage("b", 2, 50, 100)},
+			usage:        usage("c", 3, 0, 300),
+			wantCredited: 150,
+			wantReasons:  []string{RejectReasonOverlap},
+		},
+		{
+			name:         "uncovered part capped by session length",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 1000)},
+			usage:        usage("b", 2, 0, 3000),
+			wantCredited: 1500,
+			wantReasons:  []string{RejectReasonOverlap, RejectReasonSessionLength},
+		},
+		{
+			name:         "session total",
+			earlier:      []*UsageCredit{usage("a", 1, 0, 1000)},
+			usage:        usage("b", 1, 1000, 1000),
+			wantCredited: 500,
+			wantReasons:  []string{RejectReasonSessionTotal},
+		},
+		{
+			name:         "stored usage counts as earlier usage",
+			existing:     []*UsageCredit{{UserID: "user", PomodoroUsageLogID: "x", Start: 0, Duration: 100, Credited: 100}},
+			usage:        usage("a", 1, 50, 100),
+			wantCredited: 50,
+			wantReasons:  []string{RejectReasonOverlap},
+		},
+		{
+			name:         "stored credit is replayed",
+			existing:     []*UsageCredit{{UserID: "user", PomodoroUsageLogID: "a", Start: 0, Duration: 100, Credited: 40}},
+			usage:        usage("a", 1, 0, 100),
+			wantCredited: 40,
+			wantReplayed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := NewUsageLedger(tt.existing)
+			for _, earlier := range tt.earlier {
+				ledger.Credit(earlier)
+			}
+
+			credit, replayed := ledger.Credit(tt.usage)
+			if replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if credit.Credited != tt.wantCredited {
+				t.Errorf("Credited = %v, want %v", credit.Credited, tt.wantCredited)
+			}
+			if !slices.Equal(credit.Reasons, tt.wantReasons) {
+				t.Errorf("Reasons = %v, want %v", credit.Reasons, tt.wantReasons)
+			}
+			if !replayed && credit.Credited+credit.Rejected != max(tt.usage.Duration, 0) {
+				t.Errorf("Credited + Rejected = %v, want %v", credit.Credited+credit.Rejected, tt.usage.Duration)
+			}
+		})
+	}
+}
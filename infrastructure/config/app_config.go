@@ -0,0 +1,284 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	leaderboardDomain "pomocore-data/domains/leaderboard/domain"
+	mongoConfig "pomocore-data/infrastructure/mongoDB/config"
+	redisConfig "pomocore-data/infrastructure/redis/config"
+	envConfig "pomocore-data/shared/common/config"
+	"pomocore-data/shared/common/tracing"
+)
+
+// Config holds every setting of the service. Load starts from the defaults, applies the YAML file and
+// then the environment variables, so existing deployments keep working without a file.
+type Config struct {
+	Environment string                    `yaml:"environment"`
+	Mongo       mongoConfig.MongoDBConfig `yaml:"mongo"`
+	Redis       redisConfig.RedisConfig   `yaml:"redis"`
+	Streams     redisConfig.StreamsConfig `yaml:"streams"`
+	LLM         LLMConfig                 `yaml:"llm"`
+	Classifier  ClassifierConfig          `yaml:"classifier"`
+	Leaderboard LeaderboardConfig         `yaml:"leaderboard"`
+	Sessions    SessionConfig             `yaml:"sessions"`
+	Monitor     MonitorConfig             `yaml:"monitor"`
+	Intervals   IntervalConfig            `yaml:"intervals"`
+	HTTP        HTTPConfig                `yaml:"http"`
+	Health      HealthConfig              `yaml:"health"`
+	Tracing     TracingConfig             `yaml:"tracing"`
+}
+
+// LLMConfig configures the fallback classifier; without an API key usage no pattern matches stays uncategorized
+type LLMConfig struct {
+	APIKey  string        `yaml:"apiKey" secret:"true"`
+	Model   string        `yaml:"model"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type ClassifierConfig struct {
+	// Workers classify the messages of one batch in parallel
+	Workers int           `yaml:"workers"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type LeaderboardConfig struct {
+	Timezone   string   `yaml:"timezone"`
+	DaySource  string   `yaml:"daySource"`
+	KeySchemes []string `yaml:"keySchemes"`
+	// HashTag defaults to one that keeps every leaderboard key in one slot when Redis runs as a cluster
+	HashTag   string        `yaml:"hashTag"`
+	DedupeTTL time.Duration `yaml:"dedupeTTL"`
+}
+
+type SessionConfig struct {
+	// CompletionTimeout is how long an ended session waits for its usage to be categorized before it is scored anyway
+	CompletionTimeout time.Duration `yaml:"completionTimeout"`
+}
+
+// MonitorConfig sets the stream backlog levels that raise an alert; a zero threshold disables it
+type MonitorConfig struct {
+	WebhookURL                string        `yaml:"webhookURL" secret:"true"`
+	WebhookTimeout            time.Duration `yaml:"webhookTimeout"`
+	LagThreshold              int64         `yaml:"lagThreshold"`
+	PendingThreshold          int64         `yaml:"pendingThreshold"`
+	OldestPendingAgeThreshold time.Duration `yaml:"oldestPendingAgeThreshold"`
+}
+
+// IntervalConfig sets how often each periodic task runs
+type IntervalConfig struct {
+	ConsumerStats          time.Duration `yaml:"consumerStats"`
+	StreamMonitor          time.Duration `yaml:"streamMonitor"`
+	DeferredRetry          time.Duration `yaml:"deferredRetry"`
+	SessionOutboxRelay     time.Duration `yaml:"sessionOutboxRelay"`
+	GroupMembershipRefresh time.Duration `yaml:"groupMembershipRefresh"`
+}
+
+type HTTPConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+type HealthConfig struct {
+	// ConsumeTimeout is how long a read loop may go without returning from the stream before liveness fails
+	ConsumeTimeout time.Duration `yaml:"consumeTimeout"`
+}
+
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+}
+
+func Default() *Config {
+	return &Config{
+		Environment: "production",
+		Mongo:       mongoConfig.NewMongoDBConfig(),
+		Redis:       redisConfig.NewRedisConfig(),
+		Streams:     redisConfig.NewStreamsConfig(),
+		LLM: LLMConfig{
+			Model:   openai.GPT4Dot1,
+			Timeout: 30 * time.Second,
+		},
+		Classifier: ClassifierConfig{
+			Workers: 10,
+			Timeout: 2 * time.Minute,
+		},
+		Leaderboard: LeaderboardConfig{
+			Timezone:   "Asia/Seoul",
+			DaySource:  string(leaderboardDomain.DaySourceSessionDate),
+			KeySchemes: []string{"v1", "v2"},
+			DedupeTTL:  7 * 24 * time.Hour,
+		},
+		Sessions: SessionConfig{
+			CompletionTimeout: 15 * time.Minute,
+		},
+		Monitor: MonitorConfig{
+			WebhookTimeout:            5 * time.Second,
+			LagThreshold:              10000,
+			PendingThreshold:          1000,
+			OldestPendingAgeThreshold: 5 * time.Minute,
+		},
+		Intervals: IntervalConfig{
+			ConsumerStats:          time.Minute,
+			StreamMonitor:          30 * time.Second,
+			DeferredRetry:          30 * time.Second,
+			SessionOutboxRelay:     5 * time.Second,
+			GroupMembershipRefresh: time.Minute,
+		},
+		HTTP: HTTPConfig{
+			Addr: ":8080",
+		},
+		Health: HealthConfig{
+			ConsumeTimeout: 2 * time.Minute,
+		},
+		Tracing: TracingConfig{
+			Exporter: tracing.ExporterNone,
+		},
+	}
+}
+
+// Load reads the YAML file at path, skipped when empty, and the environment over the defaults.
+// The configuration is returned together with any error so it can still be printed for inspection.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := envConfig.LoadYAML(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &envConfig.Overrides{}
+	cfg.applyEnv(env)
+	cfg.resolve()
+	return cfg, errors.Join(env.Err(), cfg.Validate())
+}
+
+func (c *Config) applyEnv(env *envConfig.Overrides) {
+	env.String("APP_ENV", &c.Environment)
+	c.Mongo.ApplyEnv(env)
+	c.Redis.ApplyEnv(env)
+	c.Streams.ApplyEnv(env)
+
+	env.String("OPENAI_API_KEY", &c.LLM.APIKey)
+	env.String("LLM_MODEL", &c.LLM.Model)
+	env.Duration("LLM_TIMEOUT", &c.LLM.Timeout)
+
+	env.Int("CLASSIFIER_WORKERS", &c.Classifier.Workers)
+	env.Duration("CLASSIFIER_TIMEOUT", &c.Classifier.Timeout)
+
+	env.String("LEADERBOARD_TIMEZONE", &c.Leaderboard.Timezone)
+	env.String("LEADERBOARD_DAY_SOURCE", &c.Leaderboard.DaySource)
+	env.Strings("LEADERBOARD_KEY_SCHEMES", &c.Leaderboard.KeySchemes)
+	env.String("LEADERBOARD_HASH_TAG", &c.Leaderboard.HashTag)
+	env.Duration("LEADERBOARD_DEDUPE_TTL", &c.Leaderboard.DedupeTTL)
+
+	env.Duration("SESSION_COMPLETION_TIMEOUT", &c.Sessions.CompletionTimeout)
+
+	env.String("STREAM_ALERT_WEBHOOK_URL", &c.Monitor.WebhookURL)
+	env.Duration("STREAM_ALERT_WEBHOOK_TIMEOUT", &c.Monitor.WebhookTimeout)
+	env.Int64("STREAM_LAG_THRESHOLD", &c.Monitor.LagThreshold)
+	env.Int64("STREAM_PENDING_THRESHOLD", &c.Monitor.PendingThreshold)
+	env.Duration("STREAM_OLDEST_PENDING_AGE_THRESHOLD", &c.Monitor.OldestPendingAgeThreshold)
+
+	env.Duration("CONSUMER_STATS_INTERVAL", &c.Intervals.ConsumerStats)
+	env.Duration("STREAM_MONITOR_INTERVAL", &c.Intervals.StreamMonitor)
+	env.Duration("DEFERRED_RETRY_INTERVAL", &c.Intervals.DeferredRetry)
+	env.Duration("SESSION_OUTBOX_RELAY_INTERVAL", &c.Intervals.SessionOutboxRelay)
+	env.Duration("GROUP_MEMBERSHIP_REFRESH_INTERVAL", &c.Intervals.GroupMembershipRefresh)
+
+	env.String("HTTP_ADDR", &c.HTTP.Addr)
+	env.Duration("HEALTH_CONSUME_TIMEOUT", &c.Health.ConsumeTimeout)
+	env.String("OTEL_TRACES_EXPORTER", &c.Tracing.Exporter)
+}
+
+func (c *Config) resolve() {
+	c.Streams.Resolve()
+	if c.Leaderboard.HashTag == "" {
+		c.Leaderboard.HashTag = c.Redis.DefaultLeaderboardHashTag()
+	}
+}
+
+// Validate reports every invalid setting at once, each prefixed with its section
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(section string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", section, err))
+		}
+	}
+
+	check("mongo", c.Mongo.Validate())
+	check("redis", c.Redis.Validate())
+	check("streams", c.Streams.Validate())
+	check("llm", positive("timeout", c.LLM.Timeout))
+	if c.Classifier.Workers <= 0 {
+		check("classifier", fmt.Errorf("workers must be positive, got %d", c.Classifier.Workers))
+	}
+	check("classifier", positive("timeout", c.Classifier.Timeout))
+	_, err := c.Leaderboard.Bucketing()
+	check("leaderboard", err)
+	_, err = c.Leaderboard.KeyLayout()
+	check("leaderboard", err)
+	check("sessions", positive("completionTimeout", c.Sessions.CompletionTimeout))
+	check("monitor", positive("webhookTimeout", c.Monitor.WebhookTimeout))
+	check("intervals", errors.Join(
+		positive("consumerStats", c.Intervals.ConsumerStats),
+		positive("streamMonitor", c.Intervals.StreamMonitor),
+		positive("deferredRetry", c.Intervals.DeferredRetry),
+		positive("sessionOutboxRelay", c.Intervals.SessionOutboxRelay),
+		positive("groupMembershipRefresh", c.Intervals.GroupMembershipRefresh),
+	))
+	if c.HTTP.Addr == "" {
+		check("http", fmt.Errorf("addr (HTTP_ADDR) is required"))
+	}
+	check("health", positive("consumeTimeout", c.Health.ConsumeTimeout))
+	// A read loop pausing for backpressure only refreshes its liveness once the backoff has passed
+	for _, consumer := range c.Streams.EnabledConsumers() {
+		if consumer.BackpressureWindow > 0 && c.Health.ConsumeTimeout > 0 && consumer.BackpressureMaxBackoff >= c.Health.ConsumeTimeout {
+			check("health", fmt.Errorf("consumeTimeout %s must exceed the backpressure max backoff %s of consumer %q",
+				c.Health.ConsumeTimeout, consumer.BackpressureMaxBackoff, consumer.Name))
+		}
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		check("tracing", fmt.Errorf("unknown exporter %q, expected none, otlp or stdout", c.Tracing.Exporter))
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes the configuration as YAML with passwords, API keys and webhook URLs redacted
+func (c *Config) Print(w io.Writer) error {
+	return envConfig.PrintYAML(w, c)
+}
+
+// Bucketing assigns usage to leaderboard days in the configured timezone
+func (c *LeaderboardConfig) Bucketing() (*leaderboardDomain.Bucketing, error) {
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	daySource, err := leaderboardDomain.ParseDaySource(c.DaySource)
+	if err != nil {
+		return nil, err
+	}
+	return leaderboardDomain.NewBucketing(location, daySource), nil
+}
+
+func (c *LeaderboardConfig) KeyLayout() (leaderboardDomain.KeyLayout, error) {
+	schemes, err := leaderboardDomain.ParseKeySchemes(strings.Join(c.KeySchemes, ","))
+	if err != nil {
+		return leaderboardDomain.KeyLayout{}, err
+	}
+	return leaderboardDomain.KeyLayout{Schemes: schemes, HashTag: c.HashTag}, nil
+}
+
+func positive(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s must be positive, got %s", name, d)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadAppliesDefaultsThenFileThenEnv(t *testing.T) {
+	path := writeConfigFile(t, `
+llm:
+  model: gpt-4o-mini
+  timeout: 10s
+classifier:
+  workers: 4
+http:
+  addr: ":9000"
+`)
+	t.Setenv("LLM_TIMEOUT", "20s")
+	t.Setenv("HTTP_ADDR", ":9100")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"default kept", cfg.Classifier.Timeout, 2 * time.Minute},
+		{"default model replaced by the file", cfg.LLM.Model, "gpt-4o-mini"},
+		{"file value kept", cfg.Classifier.Workers, 4},
+		{"file value replaced by the environment", cfg.LLM.Timeout, 20 * time.Second},
+		{"file address replaced by the environment", cfg.HTTP.Addr, ":9100"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadWithoutFileUsesDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.LLM.Model != openai.GPT4Dot1 || cfg.HTTP.Addr != ":8080" || cfg.Leaderboard.HashTag != "" {
+		t.Errorf("got model %q, addr %q, hash tag %q, want the defaults", cfg.LLM.Model, cfg.HTTP.Addr, cfg.Leaderboard.HashTag)
+	}
+}
+
+func TestLoadReportsEnvParseErrors(t *testing.T) {
+	t.Setenv("CLASSIFIER_WORKERS", "ten")
+	t.Setenv("LLM_TIMEOUT", "30")
+	t.Setenv("MONGO_TRANSACTIONS", "sometimes")
+
+	cfg, err := Load("")
+	if err == nil {
+		t.Fatal("Load succeeded with unparsable variables")
+	}
+	for _, key := range []string{"CLASSIFIER_WORKERS", "LLM_TIMEOUT", "MONGO_TRANSACTIONS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not mention %s", err, key)
+		}
+	}
+	// The configuration is still returned for -print-config, with the unparsable variables ignored
+	if cfg == nil || cfg.Classifier.Workers != 10 || cfg.LLM.Timeout != 30*time.Second {
+		t.Errorf("config = %+v, want the defaults where variables did not parse", cfg)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	if _, err := Load(writeConfigFile(t, "llm:\n  modle: gpt-4o\n")); err == nil {
+		t.Error("Load accepted a misspelled key")
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("MONGO_URI", "mongodb://app:mongo-pass@db:27017/pomocore")
+	t.Setenv("REDIS_PASSWORD", "redis-pass")
+	t.Setenv("REDIS_SENTINEL_PASSWORD", "sentinel-pass")
+	t.Setenv("OPENAI_API_KEY", "sk-test-key")
+	t.Setenv("STREAM_ALERT_WEBHOOK_URL", "https://hooks.example.com/services/webhook-token")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	printed := out.String()
+
+	for _, secret := range []string{"mongo-pass", "redis-pass", "sentinel-pass", "sk-test-key", "webhook-token"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("printed configuration contains %q", secret)
+		}
+	}
+	if !strings.Contains(printed, "mongodb://app:REDACTED@db:27017/pomocore") {
+		t.Errorf("printed configuration does not keep the redacted Mongo URI:\n%s", printed)
+	}
+	if cfg.Redis.Password != "redis-pass" {
+		t.Error("Print changed the configuration")
+	}
+}
+
+func TestValidateRejectsBackoffBeyondConsumeTimeout(t *testing.T) {
+	t.Setenv("HEALTH_CONSUME_TIMEOUT", "1m")
+	t.Setenv("CONSUMER_PATTERN_MATCH_BACKPRESSURE_MAX_BACKOFF", "1m")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), `backpressure max backoff 1m0s of consumer "pattern_match"`) {
+		t.Fatalf("error = %v, want the max backoff rejected", err)
+	}
+
+	t.Setenv("CONSUMER_PATTERN_MATCH_BACKPRESSURE_MAX_BACKOFF", "59s")
+	if _, err := Load(""); err != nil {
+		t.Errorf("Load: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	envConfig "pomocore-data/shared/common/config"
+)
+
+// Flags are the configuration options every command accepts
+type Flags struct {
+	path  *string
+	print *bool
+}
+
+// RegisterFlags adds -config and -print-config to the command line; call it before flag.Parse
+func RegisterFlags() *Flags {
+	return &Flags{
+		path:  flag.String("config", envConfig.GetEnv("CONFIG_FILE", ""), "YAML config file; environment variables override its values"),
+		print: flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit"),
+	}
+}
+
+// Load returns the configuration named by the flags. The logger is not set up yet, so an invalid configuration
+// is reported on stderr before exiting; -print-config prints the configuration and exits as well.
+func (f *Flags) Load() *Config {
+	cfg, err := Load(*f.path)
+	if *f.print && cfg != nil {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", printErr)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if *f.print {
+		os.Exit(0)
+	}
+	return cfg
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pomocore-data/domains/leaderboard/application/useCase"
+	"pomocore-data/domains/leaderboard/domain"
+	"pomocore-data/shared/common/logger"
+)
+
+const (
+	defaultRankingLimit = 50
+	maxRankingLimit     = 1000
+)
+
+type GroupLeaderboardHandler struct {
+	groupLeaderboardUseCase useCase.GroupLeaderboardUseCase
+	bucketing               *domain.Bucketing
+}
+
+func NewGroupLeaderboardHandler(groupLeaderboardUseCase useCase.GroupLeaderboardUseCase, bucketing *domain.Bucketing) *GroupLeaderboardHandler {
+	return &GroupLeaderboardHandler{
+		groupLeaderboardUseCase: groupLeaderboardUseCase,
+		bucketing:               bucketing,
+	}
+}
+
+type rankingEntryResponse struct {
+	UserID string  `json:"userId"`
+	Score  float64 `json:"score"`
+	Rank   int64   `json:"rank"`
+}
+
+type groupRankingResponse struct {
+	GroupID  string                 `json:"groupId"`
+	Category string                 `json:"category"`
+	Period   string                 `json:"period"`
+	Date     string                 `json:"date"`
+	Ranking  []rankingEntryResponse `json:"ranking"`
+}
+
+func (h *GroupLeaderboardHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /leaderboards/groups/{groupId}/{category}/{period}", h.getGroupRanking)
+}
+
+// getGroupRanking serves a group's ranking; date (YYYY-MM-DD) defaults to today in the given or configured timezone
+func (h *GroupLeaderboardHandler) getGroupRanking(w http.ResponseWriter, r *http.Request) {
+	period, err := domain.ParsePeriod(r.PathValue("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	timezone := query.Get("timezone")
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			http.Error(w, "unknown timezone "+strconv.Quote(timezone), http.StatusBadRequest)
+			return
+		}
+	}
+	day := h.bucketing.Day(time.Time{}, float64(time.Now().Unix()), timezone)
+	if date := query.Get("date"); date != "" {
+		if day, err = time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := int64(defaultRankingLimit)
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.ParseInt(s, 10, 64); err != nil || limit < 1 || limit > maxRankingLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxRankingLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	groupID, category := r.PathValue("groupId"), r.PathValue("category")
+	results, err := h.groupLeaderboardUseCase.GetGroupRanking(r.Context(), groupID, category, period, day, limit)
+	if err != nil {
+		logger.Error("Failed to read group ranking", logger.WithError(err))
+		http.Error(w, "failed to read ranking", http.StatusInternalServerError)
+		return
+	}
+
+	response := groupRankingResponse{
+		GroupID:  groupID,
+		Category: category,
+		Period:   string(period),
+		Date:     day.Format("2006-01-02"),
+		Ranking:  make([]rankingEntryResponse, 0, len(results)),
+	}
+	for _, result := range results {
+		response.Ranking = append(response.Ranking, rankingEntryResponse{
+			UserID: result.UserID,
+			Score:  result.Score,
+			Rank:   result.Rank,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Warn("Failed to write group ranking response", logger.WithError(err))
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pomocore-data/domains/leaderboard/domain"
+)
+
+type fakeGroupLeaderboardUseCase struct{}
+
+func (fakeGroupLeaderboardUseCase) GetGroupRanking(ctx context.Context, groupID, category string, period domain.Period, day time.Time, limit int64) ([]*domain.LeaderboardResult, error) {
+	return nil, nil
+}
+
+func TestGetGroupRankingTimezone(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusOK},
+		{"?timezone=Asia/Seoul", http.StatusOK},
+		{"?timezone=Mars/Olympus", http.StatusBadRequest},
+		{"?timezone=Mars/Olympus&date=2025-03-01", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		mux := http.NewServeMux()
+		NewGroupLeaderboardHandler(fakeGroupLeaderboardUseCase{}, domain.NewBucketing(time.UTC, domain.DaySourceSessionDate)).Register(mux)
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/leaderboards/groups/g1/work/daily"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%q: status %d, want %d: %s", tt.query, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
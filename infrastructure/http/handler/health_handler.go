@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+// HealthCheck is one named condition a probe verifies; Check returns why it is failing, or nil
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	liveness  []HealthCheck
+	readiness []HealthCheck
+}
+
+// NewHealthHandler serves liveness checks on /healthz and readiness checks on /readyz
+func NewHealthHandler(liveness, readiness []HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		liveness:  liveness,
+		readiness: readiness,
+	}
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func (h *HealthHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, "liveness", h.liveness)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, "readiness", h.readiness)
+	})
+}
+
+// serve runs every check and answers 503 if any of them fails
+func (h *HealthHandler) serve(w http.ResponseWriter, r *http.Request, probe string, checks []HealthCheck) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	response := healthResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	for _, check := range checks {
+		if err := check.Check(ctx); err != nil {
+			logger.Warn("Health check failed",
+				zap.String("probe", probe),
+				zap.String("check", check.Name),
+				logger.WithError(err))
+			response.Checks[check.Name] = err.Error()
+			response.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		response.Checks[check.Name] = "ok"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Warn("Failed to write health response", logger.WithError(err))
+	}
+}
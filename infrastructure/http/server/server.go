@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+)
+
+// Server serves the read APIs and operational endpoints of the stream consumer
+type Server struct {
+	server *http.Server
+}
+
+func NewServer(addr string, handler http.Handler) *Server {
+	return &Server{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}
+}
+
+func (s *Server) Start() {
+	go func() {
+		logger.Info("HTTP server started", zap.String("addr", s.server.Addr))
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP server stopped unexpectedly", logger.WithError(err))
+		}
+	}()
+}
+
+func (s *Server) Stop(ctx context.Context) {
+	if err := s.server.Shutdown(ctx); err != nil {
+		logger.Error("Failed to shut down HTTP server", logger.WithError(err))
+	}
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pomocore-data/infrastructure/redis/consumer"
+)
+
+// StreamAlertWebhook posts each stream alert as JSON to a configured URL
+type StreamAlertWebhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewStreamAlertWebhook(url string, timeout time.Duration) *StreamAlertWebhook {
+	return &StreamAlertWebhook{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *StreamAlertWebhook) Notify(ctx context.Context, alert consumer.StreamAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"pomocore-data/shared/common/metrics"
+	"pomocore-data/shared/common/tracing"
+)
+
+// bulkWrite runs a bulk write in its own span and records its latency and modified document count under the operation name
+func bulkWrite(ctx context.Context, collection *mongo.Collection, operation string, models []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "mongo.bulk_write",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "mongodb"),
+			attribute.String("db.collection.name", collection.Name()),
+			attribute.String("db.operation.name", operation),
+			attribute.Int("db.operation.batch.size", len(models))))
+	defer span.End()
+
+	start := time.Now()
+	result, err := collection.BulkWrite(ctx, models)
+	metrics.MongoBulkWriteDuration.WithLabelValues(collection.Name(), operation).Observe(time.Since(start).Seconds())
+	if result != nil {
+		modified := result.ModifiedCount + result.UpsertedCount
+		metrics.MongoBulkWriteModified.WithLabelValues(collection.Name(), operation).Add(float64(modified))
+		span.SetAttributes(attribute.Int64("db.modified_count", modified))
+	}
+	tracing.RecordError(span, err)
+	return result, err
+}
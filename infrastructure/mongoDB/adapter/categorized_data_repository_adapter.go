@@ -174,7 +174,7 @@ func (a *CategorizedDataRepositoryAdapter) UpdateCategoryIDsBatch(ctx context.Co
 		return nil
 	}
 
-	result, err := a.collection.BulkWrite(ctx, operations)
+	result, err := bulkWrite(ctx, a.collection, "update_category_ids", operations)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,172 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"time"
+
+	"go.uber.org/zap"
+	"pomocore-data/shared/common/logger"
+)
+
+const deferredClassificationCollection = "deferred_classification"
+
+type DeferredClassificationRepositoryAdapter struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewDeferredClassificationRepositoryPort(db *mongo.Database) pomodoroPort.DeferredClassificationRepositoryPort {
+	return &DeferredClassificationRepositoryAdapter{
+		db:         db,
+		collection: db.Collection(deferredClassificationCollection),
+	}
+}
+
+// EnsureDeferredClassificationIndexes creates the unique usage log index the upserts rely on, the index the retry
+// claims due items from and the session lookup index
+func EnsureDeferredClassificationIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(deferredClassificationCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "pomodoroUsageLogId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "sessionDate", Value: 1}, {Key: "session", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	return err
+}
+
+func (a *DeferredClassificationRepositoryAdapter) SaveBatch(ctx context.Context, items []*model.DeferredClassification) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	operations := make([]mongo.WriteModel, 0, len(items))
+	for _, item := range items {
+		if item.ID.IsZero() {
+			item.ID = primitive.NewObjectID()
+		}
+
+		// Redelivered messages must not queue the same usage log twice
+		filter := bson.M{"pomodoroUsageLogId": item.PomodoroUsageLogID}
+		update := bson.M{"$setOnInsert": item}
+
+		operation := mongo.NewUpdateOneModel()
+		operation.SetFilter(filter)
+		operation.SetUpdate(update)
+		operation.SetUpsert(true)
+		operations = append(operations, operation)
+	}
+
+	result, err := bulkWrite(ctx, a.collection, "save_batch", operations)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Saved deferred classifications",
+		zap.Int64("upserted_count", result.UpsertedCount))
+	return nil
+}
+
+func (a *DeferredClassificationRepositoryAdapter) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.DeferredClassification, error) {
+	filter := bson.M{
+		"status":        model.DeferredStatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{
+		"nextAttemptAt": now.Add(lease),
+		"updatedAt":     now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	claimed := make([]*model.DeferredClassification, 0, limit)
+	for len(claimed) < limit {
+		var item model.DeferredClassification
+		err := a.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&item)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			}
+			return claimed, err
+		}
+		claimed = append(claimed, &item)
+	}
+
+	return claimed, nil
+}
+
+func (a *DeferredClassificationRepositoryAdapter) MarkResolved(ctx context.Context, id primitive.ObjectID, category string) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"status":    model.DeferredStatusResolved,
+		"category":  category,
+		"updatedAt": time.Now(),
+	}}
+
+	_, err := a.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (a *DeferredClassificationRepositoryAdapter) Reschedule(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"attempts":      attempts,
+		"nextAttemptAt": nextAttemptAt,
+		"lastError":     lastError,
+		"updatedAt":     time.Now(),
+	}}
+
+	_, err := a.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (a *DeferredClassificationRepositoryAdapter) MarkFailed(ctx context.Context, id primitive.ObjectID, attempts int, lastError string) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"status":    model.DeferredStatusFailed,
+		"attempts":  attempts,
+		"lastError": lastError,
+		"updatedAt": time.Now(),
+	}}
+
+	_, err := a.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (a *DeferredClassificationRepositoryAdapter) CountPendingBySession(ctx context.Context, userID string, sessionDate time.Time, session int) (int64, error) {
+	filter := bson.M{
+		"userId":      userID,
+		"sessionDate": sessionDate,
+		"session":     session,
+		"status":      model.DeferredStatusPending,
+	}
+
+	return a.collection.CountDocuments(ctx, filter)
+}
+
+func (a *DeferredClassificationRepositoryAdapter) FindResolvedBySession(ctx context.Context, userID string, sessionDate time.Time, session int) ([]*model.DeferredClassification, error) {
+	filter := bson.M{
+		"userId":      userID,
+		"sessionDate": sessionDate,
+		"session":     session,
+		"status":      model.DeferredStatusResolved,
+	}
+
+	cursor, err := a.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []*model.DeferredClassification
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
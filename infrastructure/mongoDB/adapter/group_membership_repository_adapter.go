@@ -0,0 +1,41 @@
+package adapter
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	leaderboardPort "pomocore-data/domains/leaderboard/application/port"
+	"pomocore-data/infrastructure/mongoDB/model"
+)
+
+type GroupMembershipRepositoryAdapter struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewGroupMembershipRepositoryPort(db *mongo.Database) leaderboardPort.GroupMembershipRepositoryPort {
+	return &GroupMembershipRepositoryAdapter{
+		db:         db,
+		collection: db.Collection("group_member"),
+	}
+}
+
+func (a *GroupMembershipRepositoryAdapter) FindAllGroupIDsByUser(ctx context.Context) (map[string][]string, error) {
+	opts := options.Find().SetProjection(bson.M{"groupId": 1, "userId": 1})
+	cursor, err := a.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groupIDsByUser := make(map[string][]string)
+	for cursor.Next(ctx) {
+		var member model.GroupMember
+		if err := cursor.Decode(&member); err != nil {
+			return nil, err
+		}
+		groupIDsByUser[member.UserID] = append(groupIDsByUser[member.UserID], member.GroupID.Hex())
+	}
+	return groupIDsByUser, cursor.Err()
+}
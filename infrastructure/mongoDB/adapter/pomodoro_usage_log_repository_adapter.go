@@ -6,6 +6,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
 	"pomocore-data/infrastructure/mongoDB/model"
 	"pomocore-data/shared/common/logger"
@@ -145,7 +146,7 @@ func (a *PomodoroUsageLogRepositoryAdapter) UpdateCategorizedDataIDsBatch(ctx co
 		return nil
 	}
 
-	result, err := a.collection.BulkWrite(ctx, operations)
+	result, err := bulkWrite(ctx, a.collection, "update_categorized_data_ids", operations)
 	if err != nil {
 		return err
 	}
@@ -184,7 +185,7 @@ func (a *PomodoroUsageLogRepositoryAdapter) UpdateCategoryIDsBatch(ctx context.C
 		return nil
 	}
 
-	result, err := a.collection.BulkWrite(ctx, operations)
+	result, err := bulkWrite(ctx, a.collection, "update_category_ids", operations)
 	if err != nil {
 		return err
 	}
@@ -193,3 +194,41 @@ func (a *PomodoroUsageLogRepositoryAdapter) UpdateCategoryIDsBatch(ctx context.C
 		zap.Int64("modified_count", result.ModifiedCount))
 	return nil
 }
+
+func (a *PomodoroUsageLogRepositoryAdapter) CountUncategorizedBySession(ctx context.Context, userID string, sessionDate time.Time, session int) (int64, error) {
+	filter := bson.M{
+		"userId":      userID,
+		"sessionDate": sessionDate,
+		"session":     session,
+		"$or": []bson.M{
+			{"categoryId": bson.M{"$exists": false}},
+			{"categoryId": nil},
+			{"categoryId": primitive.NilObjectID},
+		},
+	}
+
+	return a.collection.CountDocuments(ctx, filter)
+}
+
+func (a *PomodoroUsageLogRepositoryAdapter) ForEach(ctx context.Context, fn func(log *model.PomodoroUsageLog) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "userId", Value: 1}, {Key: "sessionDate", Value: 1}, {Key: "timestamp", Value: 1}}).
+		SetAllowDiskUse(true)
+
+	cursor, err := a.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var usageLog model.PomodoroUsageLog
+		if err := cursor.Decode(&usageLog); err != nil {
+			return err
+		}
+		if err := fn(&usageLog); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,248 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"pomocore-data/domains/message"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	"pomocore-data/domains/pomodoro/domain"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"time"
+)
+
+const (
+	sessionScoreOutboxCollection = "session_score_outbox"
+	// Rows of sessions that never end expire after activityRetention, everything else after eventRetention
+	activityRetention = 3 * time.Hour
+	eventRetention    = 7 * 24 * time.Hour
+)
+
+type SessionScoreOutboxRepositoryAdapter struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewSessionScoreOutboxRepositoryPort(db *mongo.Database) pomodoroPort.SessionScoreOutboxRepositoryPort {
+	return &SessionScoreOutboxRepositoryAdapter{
+		db:         db,
+		collection: db.Collection(sessionScoreOutboxCollection),
+	}
+}
+
+// EnsureSessionScoreOutboxIndexes creates the unique event index and the TTL index used for cleanup
+func EnsureSessionScoreOutboxIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(sessionScoreOutboxCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "eventId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "ended", Value: 1}}},
+		{Keys: bson.D{{Key: "expireAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) RecordActivities(ctx context.Context, activities []*domain.SessionActivity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(activities))
+	for _, activity := range activities {
+		insertFields := newOutboxRowFields(activity.UserID, activity.SessionDate, activity.Session, now)
+		delete(insertFields, "expireAt")
+
+		filter := bson.M{"eventId": message.SessionEventID(activity.UserID, activity.SessionDate, activity.Session)}
+		update := bson.M{
+			"$setOnInsert": insertFields,
+			// Each activity keeps an unfinished session alive without cutting short the retention of an ended one
+			"$max": bson.M{"expireAt": now.Add(activityRetention)},
+			"$set": bson.M{
+				"activities." + activity.PomodoroUsageLogID: model.SessionOutboxActivity{
+					App:        activity.App,
+					Category:   activity.Category,
+					Duration:   activity.Duration,
+					Timestamp:  activity.Timestamp,
+					IsLLMBased: activity.IsLLMBased,
+				},
+				"updatedAt": now,
+			},
+		}
+
+		operation := mongo.NewUpdateOneModel()
+		operation.SetFilter(filter)
+		operation.SetUpdate(update)
+		operation.SetUpsert(true)
+		operations = append(operations, operation)
+	}
+
+	_, err := bulkWrite(ctx, a.collection, "record_activities", operations)
+	return err
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) MarkEnded(ctx context.Context, sessionScoreMsgs []*message.SessionScoreMessage) error {
+	if len(sessionScoreMsgs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(sessionScoreMsgs))
+	for _, msg := range sessionScoreMsgs {
+		insertFields := newOutboxRowFields(msg.UserID, msg.SessionDate, msg.Session, now)
+		delete(insertFields, "expireAt")
+
+		filter := bson.M{"eventId": msg.EventID}
+		update := bson.M{
+			"$setOnInsert": insertFields,
+			"$set": bson.M{
+				"ended":     true,
+				"expireAt":  now.Add(eventRetention),
+				"updatedAt": now,
+			},
+			"$min": bson.M{"endedAt": now},
+		}
+
+		operation := mongo.NewUpdateOneModel()
+		operation.SetFilter(filter)
+		operation.SetUpdate(update)
+		operation.SetUpsert(true)
+		operations = append(operations, operation)
+	}
+
+	_, err := bulkWrite(ctx, a.collection, "mark_ended", operations)
+	return err
+}
+
+func newOutboxRowFields(userID string, sessionDate time.Time, session int, now time.Time) bson.M {
+	return bson.M{
+		"userId":      userID,
+		"sessionDate": sessionDate,
+		"session":     session,
+		"status":      model.OutboxStatusPending,
+		"attempts":    0,
+		"expireAt":    now.Add(activityRetention),
+		"createdAt":   now,
+	}
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) FindAwaiting(ctx context.Context, limit int) ([]*model.SessionScoreOutbox, error) {
+	filter := bson.M{"status": model.OutboxStatusPending, "ended": true}
+	opts := options.Find().SetSort(bson.M{"endedAt": 1}).SetLimit(int64(limit))
+	return a.find(ctx, filter, opts)
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) FindAwaitingByEventIDs(ctx context.Context, eventIDs []string) ([]*model.SessionScoreOutbox, error) {
+	if len(eventIDs) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"eventId": bson.M{"$in": eventIDs},
+		"status":  model.OutboxStatusPending,
+		"ended":   true,
+	}
+	return a.find(ctx, filter)
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) MarkReady(ctx context.Context, id primitive.ObjectID, payload *message.SessionScoreMessage) error {
+	now := time.Now()
+	filter := bson.M{"_id": id, "status": model.OutboxStatusPending}
+	update := bson.M{"$set": bson.M{
+		"status":        model.OutboxStatusReady,
+		"payload":       payload,
+		"nextAttemptAt": now,
+		"updatedAt":     now,
+	}}
+
+	_, err := a.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) ClaimReady(ctx context.Context, now time.Time, lease time.Duration, maxAttempts int, limit int) ([]*model.SessionScoreOutbox, error) {
+	filter := bson.M{
+		"status":        model.OutboxStatusReady,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"attempts":      bson.M{"$lt": maxAttempts},
+	}
+	update := bson.M{
+		"$set": bson.M{"nextAttemptAt": now.Add(lease), "updatedAt": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	claimed := make([]*model.SessionScoreOutbox, 0, limit)
+	for len(claimed) < limit {
+		var row model.SessionScoreOutbox
+		err := a.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&row)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			}
+			return claimed, err
+		}
+		claimed = append(claimed, &row)
+	}
+
+	return claimed, nil
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) ParkExhausted(ctx context.Context, now time.Time, maxAttempts int) (int64, error) {
+	filter := bson.M{
+		"status":        model.OutboxStatusReady,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"attempts":      bson.M{"$gte": maxAttempts},
+	}
+	update := bson.M{"$set": bson.M{
+		"status":    model.OutboxStatusFailed,
+		"expireAt":  now.Add(eventRetention),
+		"updatedAt": now,
+	}}
+
+	result, err := a.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) MarkSent(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"status":    model.OutboxStatusSent,
+		"expireAt":  now.Add(eventRetention),
+		"updatedAt": now,
+	}}
+
+	_, err := a.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) MarkPublishFailed(ctx context.Context, id primitive.ObjectID, lastError string) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"lastError": lastError,
+		"updatedAt": time.Now(),
+	}}
+
+	_, err := a.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (a *SessionScoreOutboxRepositoryAdapter) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*model.SessionScoreOutbox, error) {
+	cursor, err := a.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []*model.SessionScoreOutbox
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	"pomocore-data/shared/common/logger"
+	"sync/atomic"
+)
+
+// illegalOperationCode is returned by standalone servers, which do not support transactions
+const illegalOperationCode = 20
+
+type TransactionAdapter struct {
+	client   *mongo.Client
+	disabled atomic.Bool
+}
+
+func NewTransactionPort(client *mongo.Client, enabled bool) pomodoroPort.TransactionPort {
+	adapter := &TransactionAdapter{client: client}
+	adapter.disabled.Store(!enabled)
+	return adapter
+}
+
+func (a *TransactionAdapter) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if a.disabled.Load() {
+		return fn(ctx)
+	}
+
+	session, err := a.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == illegalOperationCode {
+		logger.Warn("MongoDB does not support transactions, falling back to non-transactional writes", logger.WithError(err))
+		a.disabled.Store(true)
+		return fn(ctx)
+	}
+	return err
+}
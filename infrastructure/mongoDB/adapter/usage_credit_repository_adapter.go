@@ -0,0 +1,145 @@
+package adapter
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+	"pomocore-data/domains/pomodoro/domain"
+	"pomocore-data/infrastructure/mongoDB/model"
+	"time"
+)
+
+const usageCreditCollection = "usage_credit"
+
+type UsageCreditRepositoryAdapter struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewUsageCreditRepositoryPort(db *mongo.Database) pomodoroPort.UsageCreditRepositoryPort {
+	return &UsageCreditRepositoryAdapter{
+		db:         db,
+		collection: db.Collection(usageCreditCollection),
+	}
+}
+
+// EnsureUsageCreditIndexes creates the unique usage log index and the window and session lookup indexes
+func EnsureUsageCreditIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(usageCreditCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "pomodoroUsageLogId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "start", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "sessionDate", Value: 1}, {Key: "session", Value: 1}}},
+	})
+	return err
+}
+
+func (a *UsageCreditRepositoryAdapter) FindInWindows(ctx context.Context, windows []pomodoroPort.UsageCreditWindow) ([]*domain.UsageCredit, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	conditions := make(bson.A, 0, len(windows))
+	for _, window := range windows {
+		conditions = append(conditions, bson.M{
+			"userId": window.UserID,
+			"start":  bson.M{"$lte": window.To},
+			"end":    bson.M{"$gte": window.From},
+		})
+	}
+
+	return a.find(ctx, bson.M{"$or": conditions})
+}
+
+func (a *UsageCreditRepositoryAdapter) FindBySessions(ctx context.Context, sessions []pomodoroPort.UsageCreditSession) ([]*domain.UsageCredit, error) {
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	conditions := make(bson.A, 0, len(sessions))
+	for _, session := range sessions {
+		conditions = append(conditions, bson.M{
+			"userId":      session.UserID,
+			"sessionDate": session.SessionDate,
+			"session":     session.Session,
+		})
+	}
+
+	return a.find(ctx, bson.M{"$or": conditions})
+}
+
+func (a *UsageCreditRepositoryAdapter) FindByUsageLogIDs(ctx context.Context, usageLogIDs []string) ([]*domain.UsageCredit, error) {
+	if len(usageLogIDs) == 0 {
+		return nil, nil
+	}
+	return a.find(ctx, bson.M{"pomodoroUsageLogId": bson.M{"$in": usageLogIDs}})
+}
+
+func (a *UsageCreditRepositoryAdapter) find(ctx context.Context, filter bson.M) ([]*domain.UsageCredit, error) {
+	cursor, err := a.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []*model.UsageCredit
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	credits := make([]*domain.UsageCredit, 0, len(rows))
+	for _, row := range rows {
+		credits = append(credits, &domain.UsageCredit{
+			UserID:             row.UserID,
+			PomodoroUsageLogID: row.PomodoroUsageLogID,
+			SessionDate:        row.SessionDate,
+			Session:            row.Session,
+			SessionMinutes:     row.SessionMinutes,
+			Start:              row.Start,
+			Duration:           row.Duration,
+			Credited:           row.Credited,
+			Rejected:           row.Rejected,
+			Reasons:            row.Reasons,
+			Category:           row.Category,
+			LeaderboardDay:     row.LeaderboardDay,
+		})
+	}
+	return credits, nil
+}
+
+func (a *UsageCreditRepositoryAdapter) SaveBatch(ctx context.Context, credits []*domain.UsageCredit) error {
+	if len(credits) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(credits))
+	for _, credit := range credits {
+		row := model.UsageCredit{
+			UserID:             credit.UserID,
+			PomodoroUsageLogID: credit.PomodoroUsageLogID,
+			SessionDate:        credit.SessionDate,
+			Session:            credit.Session,
+			SessionMinutes:     credit.SessionMinutes,
+			Start:              credit.Start,
+			End:                credit.End(),
+			Duration:           credit.Duration,
+			Credited:           credit.Credited,
+			Rejected:           credit.Rejected,
+			Reasons:            credit.Reasons,
+			Category:           credit.Category,
+			LeaderboardDay:     credit.LeaderboardDay,
+			CreatedAt:          now,
+		}
+
+		operation := mongo.NewUpdateOneModel()
+		operation.SetFilter(bson.M{"pomodoroUsageLogId": credit.PomodoroUsageLogID})
+		operation.SetUpdate(bson.M{"$setOnInsert": row})
+		operation.SetUpsert(true)
+		operations = append(operations, operation)
+	}
+
+	_, err := bulkWrite(ctx, a.collection, "save_batch", operations)
+	return err
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	envConfig "pomocore-data/shared/common/config"
@@ -11,21 +12,38 @@ import (
 )
 
 type MongoDBConfig struct {
-	URL      string
-	Database string
+	URL      string `yaml:"uri" secret:"url"`
+	Database string `yaml:"database"`
+	// Transactions can be turned off for standalone servers, which do not support them
+	Transactions bool `yaml:"transactions"`
 }
 
-func NewMongoDBConfig() *MongoDBConfig {
-	envConfig.LoadEnv()
-	return &MongoDBConfig{
-		URL:      envConfig.GetEnv("MONGO_URI", "mongodb://localhost:27017"),
-		Database: envConfig.GetEnv("MONGO_DATABASE", "pomocore"),
+// NewMongoDBConfig returns the defaults, to be overridden by the config file and ApplyEnv
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		URL:          "mongodb://localhost:27017",
+		Database:     "pomocore",
+		Transactions: true,
 	}
 }
 
-func ConnectMongoDB() (*mongo.Client, error) {
-	config := NewMongoDBConfig()
+func (c *MongoDBConfig) ApplyEnv(env *envConfig.Overrides) {
+	env.String("MONGO_URI", &c.URL)
+	env.String("MONGO_DATABASE", &c.Database)
+	env.Bool("MONGO_TRANSACTIONS", &c.Transactions)
+}
+
+func (c *MongoDBConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("uri (MONGO_URI) is required")
+	}
+	if c.Database == "" {
+		return fmt.Errorf("database (MONGO_DATABASE) is required")
+	}
+	return nil
+}
 
+func ConnectMongoDB(config *MongoDBConfig) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
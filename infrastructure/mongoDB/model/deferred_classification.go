@@ -0,0 +1,67 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	DeferredStatusPending  = "pending"
+	DeferredStatusResolved = "resolved"
+	DeferredStatusFailed   = "failed"
+)
+
+type DeferredClassification struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty"`
+	UserID             string             `bson:"userId"`
+	PomodoroUsageLogID primitive.ObjectID `bson:"pomodoroUsageLogId"`
+	CategorizedDataID  primitive.ObjectID `bson:"categorizedDataId"`
+	App                string             `bson:"app"`
+	Title              string             `bson:"title"`
+	URL                string             `bson:"url"`
+	Session            int                `bson:"session"`
+	SessionDate        time.Time          `bson:"sessionDate"`
+	Duration           float64            `bson:"duration"`
+	Timestamp          float64            `bson:"timestamp"`
+	LeaderboardDay     time.Time          `bson:"leaderboardDay,omitempty"`
+	Status             string             `bson:"status"`
+	Attempts           int                `bson:"attempts"`
+	NextAttemptAt      time.Time          `bson:"nextAttemptAt"`
+	LastError          string             `bson:"lastError,omitempty"`
+	Category           string             `bson:"category,omitempty"`
+	CreatedAt          time.Time          `bson:"createdAt"`
+	UpdatedAt          time.Time          `bson:"updatedAt"`
+}
+
+func NewDeferredClassification(
+	userID string,
+	pomodoroUsageLogID primitive.ObjectID,
+	categorizedDataID primitive.ObjectID,
+	app, title, url string,
+	session int,
+	sessionDate time.Time,
+	duration float64,
+	timestamp float64,
+	leaderboardDay time.Time,
+	nextAttemptAt time.Time,
+) *DeferredClassification {
+	now := time.Now()
+	return &DeferredClassification{
+		ID:                 primitive.NewObjectID(),
+		UserID:             userID,
+		PomodoroUsageLogID: pomodoroUsageLogID,
+		CategorizedDataID:  categorizedDataID,
+		App:                app,
+		Title:              title,
+		URL:                url,
+		Session:            session,
+		SessionDate:        sessionDate,
+		Duration:           duration,
+		Timestamp:          timestamp,
+		LeaderboardDay:     leaderboardDay,
+		Status:             DeferredStatusPending,
+		NextAttemptAt:      nextAttemptAt,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+}
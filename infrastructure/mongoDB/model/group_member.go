@@ -0,0 +1,11 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GroupMember struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	GroupID primitive.ObjectID `bson:"groupId"`
+	UserID  string             `bson:"userId"`
+}
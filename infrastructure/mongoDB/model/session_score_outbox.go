@@ -0,0 +1,44 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"pomocore-data/domains/message"
+	"time"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusReady   = "ready"
+	OutboxStatusSent    = "sent"
+	// OutboxStatusFailed rows used every publish attempt and are kept for inspection instead of being retried
+	OutboxStatusFailed = "failed"
+)
+
+// SessionScoreOutbox collects a session's classified activities and, once the session is complete,
+// the session score event to be relayed to the stream
+type SessionScoreOutbox struct {
+	ID            primitive.ObjectID               `bson:"_id,omitempty"`
+	EventID       string                           `bson:"eventId"`
+	UserID        string                           `bson:"userId"`
+	SessionDate   time.Time                        `bson:"sessionDate"`
+	Session       int                              `bson:"session"`
+	Status        string                           `bson:"status"`
+	Ended         bool                             `bson:"ended"`
+	EndedAt       time.Time                        `bson:"endedAt,omitempty"`
+	Activities    map[string]SessionOutboxActivity `bson:"activities"`
+	Payload       *message.SessionScoreMessage     `bson:"payload,omitempty"`
+	Attempts      int                              `bson:"attempts"`
+	NextAttemptAt time.Time                        `bson:"nextAttemptAt,omitempty"`
+	LastError     string                           `bson:"lastError,omitempty"`
+	ExpireAt      time.Time                        `bson:"expireAt"`
+	CreatedAt     time.Time                        `bson:"createdAt"`
+	UpdatedAt     time.Time                        `bson:"updatedAt"`
+}
+
+type SessionOutboxActivity struct {
+	App        string  `bson:"app"`
+	Category   string  `bson:"category"`
+	Duration   float64 `bson:"duration"`
+	Timestamp  float64 `bson:"timestamp"`
+	IsLLMBased bool    `bson:"isLLMBased"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// UsageCredit records how much of a usage log's duration was credited to the leaderboards, and to which day.
+// Rows are kept so leaderboards can be rebuilt from them.
+type UsageCredit struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty"`
+	UserID             string             `bson:"userId"`
+	PomodoroUsageLogID string             `bson:"pomodoroUsageLogId"`
+	SessionDate        time.Time          `bson:"sessionDate"`
+	Session            int                `bson:"session"`
+	SessionMinutes     int                `bson:"sessionMinutes"`
+	Start              float64            `bson:"start"`
+	End                float64            `bson:"end"`
+	Duration           float64            `bson:"duration"`
+	Credited           float64            `bson:"credited"`
+	Rejected           float64            `bson:"rejected"`
+	Reasons            []string           `bson:"reasons,omitempty"`
+	Category           string             `bson:"category,omitempty"`
+	LeaderboardDay     time.Time          `bson:"leaderboardDay,omitempty"`
+	CreatedAt          time.Time          `bson:"createdAt"`
+}
@@ -4,60 +4,168 @@ import (
 	"context"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"pomocore-data/domains/leaderboard/application/port"
 	"pomocore-data/domains/leaderboard/domain"
+	"pomocore-data/shared/common/metrics"
+	"pomocore-data/shared/common/tracing"
+	"time"
 )
 
+const (
+	replaceChunkSize = 1000
+	// The dedupe marker shares the board prefix so it hashes to the same cluster slot
+	dedupeKeyFormat = "%s-dedupe:%s"
+)
+
+// boardRetention is how long a board lives after its first write; periods not listed never expire
+var boardRetention = map[domain.Period]time.Duration{
+	domain.PeriodDaily:   35 * 24 * time.Hour,
+	domain.PeriodWeekly:  180 * 24 * time.Hour,
+	domain.PeriodMonthly: 2 * 365 * 24 * time.Hour,
+}
+
+type scriptCall struct {
+	keys []string
+	args []interface{}
+}
+
 type LeaderboardCacheAdapter struct {
-	client    *redis.Client
-	keyFormat string
+	client    redis.UniversalClient
+	keyLayout domain.KeyLayout
+	dedupeTTL time.Duration
 }
 
-func NewLeaderboardCachePort(client *redis.Client) port.LeaderboardCachePort {
+func NewLeaderboardCachePort(client redis.UniversalClient, keyLayout domain.KeyLayout, dedupeTTL time.Duration) port.LeaderboardCachePort {
 	return &LeaderboardCacheAdapter{
 		client:    client,
-		keyFormat: "leaderboard:%s:%s",
+		keyLayout: keyLayout,
+		dedupeTTL: dedupeTTL,
 	}
 }
 
+// BatchIncreaseScore applies each entry with one script call, which checks every board before updating any.
+// Entries with an ID are applied at most once within the dedupe TTL.
 func (a *LeaderboardCacheAdapter) BatchIncreaseScore(ctx context.Context, entries []*domain.LeaderboardEntry) error {
-	pipe := a.client.Pipeline()
+	if len(entries) == 0 {
+		return nil
+	}
 
-	keyScores := make(map[string][]redis.Z)
+	ctx, span := tracing.Tracer().Start(ctx, "leaderboard.batch_increase_score",
+		trace.WithAttributes(attribute.Int("leaderboard.entry_count", len(entries))))
+	defer span.End()
 
+	calls := make([]scriptCall, 0, len(entries))
 	for _, entry := range entries {
-		keys := entry.GetCategoryLeaderboardKeys()
-		if entry.IsWorkCategory() {
-			keys = append(keys, entry.GetWorkLeaderboardKeys()...)
-		}
+		calls = append(calls, a.incrementCall(entry))
+	}
 
-		for _, key := range keys {
-			if keyScores[key] == nil {
-				keyScores[key] = make([]redis.Z, 0)
-			}
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.Cmd, len(calls))
+	for i, call := range calls {
+		cmds[i] = leaderboardIncrementScript.EvalSha(ctx, pipe, call.keys, call.args...)
+	}
+	_, _ = pipe.Exec(ctx)
 
-			keyScores[key] = append(keyScores[key], redis.Z{
-				Score:  entry.Duration,
-				Member: entry.UserID,
-			})
+	// The script cache is empty after a restart or failover; Run loads the script and retries
+	failed := 0
+	var lastErr error
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		if err != nil && redis.HasErrorPrefix(err, "NOSCRIPT") {
+			err = leaderboardIncrementScript.Run(ctx, a.client, calls[i].keys, calls[i].args...).Err()
 		}
+		if err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+	if failed > 0 {
+		metrics.LeaderboardErrors.WithLabelValues("increment").Add(float64(failed))
+		err := fmt.Errorf("failed to apply %d of %d leaderboard entries: %w", failed, len(entries), lastErr)
+		span.SetAttributes(attribute.Int("leaderboard.failed_count", failed))
+		tracing.RecordError(span, err)
+		return err
 	}
+	return nil
+}
 
-	for key, scores := range keyScores {
-		memberScores := make(map[string]float64)
-		for _, score := range scores {
-			memberScores[score.Member.(string)] += score.Score
+func (a *LeaderboardCacheAdapter) incrementCall(entry *domain.LeaderboardEntry) scriptCall {
+	dedupeTTL := int64(a.dedupeTTL.Seconds())
+	if entry.EntryID == "" {
+		dedupeTTL = 0
+	}
+	call := scriptCall{
+		keys: []string{fmt.Sprintf(dedupeKeyFormat, a.keyLayout.Prefix(), entry.EntryID)},
+		args: []interface{}{entry.UserID, entry.Duration, dedupeTTL},
+	}
+
+	for _, period := range domain.AllPeriods {
+		ttl := int64(boardRetention[period].Seconds())
+		for _, key := range entry.GetLeaderboardKeys(a.keyLayout, []domain.Period{period}) {
+			call.keys = append(call.keys, key)
+			call.args = append(call.args, ttl)
 		}
+	}
+	return call
+}
 
-		for member, totalScore := range memberScores {
-			pipe.ZIncrBy(ctx, key, totalScore, member)
+// ReplaceScores builds the board under a temporary key and renames it over the live one. RENAME carries the
+// temporary key's TTL over, so the board's remaining retention, or the period's full one for a new board, is set first.
+func (a *LeaderboardCacheAdapter) ReplaceScores(ctx context.Context, key string, period domain.Period, scores map[string]float64) error {
+	tmpKey := key + ":rebuild"
+	if err := a.client.Del(ctx, tmpKey).Err(); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", tmpKey, err)
+	}
+	if len(scores) == 0 {
+		return a.client.Del(ctx, key).Err()
+	}
+
+	members := make([]redis.Z, 0, len(scores))
+	for member, score := range scores {
+		members = append(members, redis.Z{Score: score, Member: member})
+	}
+	for start := 0; start < len(members); start += replaceChunkSize {
+		end := min(start+replaceChunkSize, len(members))
+		if err := a.client.ZAdd(ctx, tmpKey, members[start:end]...).Err(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", tmpKey, err)
 		}
 	}
 
-	_, err := pipe.Exec(ctx)
+	ttl, err := a.client.PTTL(ctx, key).Result()
 	if err != nil {
-		return fmt.Errorf("failed to execute batch score increase: %w", err)
+		return fmt.Errorf("failed to read TTL of %s: %w", key, err)
+	}
+	if ttl <= 0 {
+		ttl = boardRetention[period]
+	}
+	if ttl > 0 {
+		if err := a.client.PExpire(ctx, tmpKey, ttl).Err(); err != nil {
+			return fmt.Errorf("failed to set TTL of %s: %w", tmpKey, err)
+		}
 	}
 
+	if err := a.client.Rename(ctx, tmpKey, key).Err(); err != nil {
+		return fmt.Errorf("failed to swap %s: %w", key, err)
+	}
 	return nil
 }
+
+func (a *LeaderboardCacheAdapter) GetTopRanking(ctx context.Context, key string, limit int64) ([]*domain.LeaderboardResult, error) {
+	members, err := a.client.ZRevRangeWithScores(ctx, key, 0, limit-1).Result()
+	if err != nil {
+		metrics.LeaderboardErrors.WithLabelValues("read_ranking").Inc()
+		return nil, fmt.Errorf("failed to read ranking %s: %w", key, err)
+	}
+
+	results := make([]*domain.LeaderboardResult, 0, len(members))
+	for i, member := range members {
+		results = append(results, &domain.LeaderboardResult{
+			UserID: member.Member.(string),
+			Score:  member.Score,
+			Rank:   int64(i + 1),
+		})
+	}
+	return results, nil
+}
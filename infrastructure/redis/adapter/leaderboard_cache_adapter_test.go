@@ -0,0 +1,120 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"pomocore-data/domains/leaderboard/domain"
+)
+
+func TestIncrementCallKeepsEveryKeyInTheTaggedSlot(t *testing.T) {
+	layout := domain.KeyLayout{Schemes: []domain.KeyScheme{domain.KeySchemeV1, domain.KeySchemeV2}, HashTag: "lb"}
+	a := &LeaderboardCacheAdapter{keyLayout: layout, dedupeTTL: time.Hour}
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, entryID := range []string{"usage-log", ""} {
+		entry := domain.NewLeaderboardEntry(entryID, "user", "Development", 60, 0, day)
+		entry.GroupIDs = []string{"group"}
+		call := a.incrementCall(entry)
+
+		for _, key := range call.keys {
+			if !strings.HasPrefix(key, "leaderboard:{lb}") {
+				t.Errorf("entry %q: key %q is outside the leaderboard:{lb} slot", entryID, key)
+			}
+		}
+	}
+}
+
+func TestBatchIncreaseScoreAppliesEntryOnce(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	layout := domain.KeyLayout{Schemes: []domain.KeyScheme{domain.KeySchemeV2}, HashTag: "lb"}
+	cache := NewLeaderboardCachePort(client, layout, time.Hour)
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	board := domain.NewLeaderboardEntry("", "user", "Development", 0, 0, day).GetCategoryLeaderboardKeys(layout, []domain.Period{domain.PeriodAllTime})[0]
+
+	withID := domain.NewLeaderboardEntry("usage-log", "user", "Development", 60, 0, day)
+	withoutID := domain.NewLeaderboardEntry("", "user", "Development", 5, 0, day)
+	for i := 0; i < 2; i++ {
+		if err := cache.BatchIncreaseScore(ctx, []*domain.LeaderboardEntry{withID, withoutID}); err != nil {
+			t.Fatalf("BatchIncreaseScore: %v", err)
+		}
+	}
+
+	if got := client.ZScore(ctx, board, "user").Val(); got != 70 {
+		t.Errorf("score = %v, want 70 (entry with ID once, entry without ID twice)", got)
+	}
+}
+
+func TestBatchIncreaseScoreRetriesEntryAfterFailedBoard(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	layout := domain.KeyLayout{Schemes: []domain.KeyScheme{domain.KeySchemeV2}, HashTag: "lb"}
+	cache := NewLeaderboardCachePort(client, layout, time.Hour)
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	entry := domain.NewLeaderboardEntry("usage-log", "user", "Development", 60, 0, day)
+	boards := entry.GetLeaderboardKeys(layout, domain.AllPeriods)
+
+	// A board of the wrong type fails the entry before any board is changed
+	broken := boards[len(boards)-1]
+	client.Set(ctx, broken, "not a board", 0)
+	if err := cache.BatchIncreaseScore(ctx, []*domain.LeaderboardEntry{entry}); err == nil {
+		t.Fatal("BatchIncreaseScore succeeded with a board of the wrong type")
+	}
+	for _, board := range boards[:len(boards)-1] {
+		if client.Exists(ctx, board).Val() != 0 {
+			t.Errorf("%s was changed by the failed entry", board)
+		}
+	}
+
+	// The retry is not skipped as already applied
+	client.Del(ctx, broken)
+	if err := cache.BatchIncreaseScore(ctx, []*domain.LeaderboardEntry{entry}); err != nil {
+		t.Fatalf("BatchIncreaseScore: %v", err)
+	}
+	for _, board := range boards {
+		if got := client.ZScore(ctx, board, "user").Val(); got != 60 {
+			t.Errorf("%s: score = %v, want 60", board, got)
+		}
+	}
+}
+
+func TestReplaceScoresKeepsRetention(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+	cache := NewLeaderboardCachePort(client, domain.KeyLayout{Schemes: []domain.KeyScheme{domain.KeySchemeV2}}, time.Hour)
+
+	// A live board keeps what is left of its retention
+	client.ZAdd(ctx, "leaderboard:live", redis.Z{Score: 1, Member: "user"})
+	client.Expire(ctx, "leaderboard:live", time.Hour)
+	// A new board gets the period's retention
+	tests := map[string]time.Duration{
+		"leaderboard:live": time.Hour,
+		"leaderboard:new":  boardRetention[domain.PeriodDaily],
+	}
+
+	for key, wantTTL := range tests {
+		if err := cache.ReplaceScores(ctx, key, domain.PeriodDaily, map[string]float64{"user": 10}); err != nil {
+			t.Fatalf("ReplaceScores(%s): %v", key, err)
+		}
+		if got := client.ZScore(ctx, key, "user").Val(); got != 10 {
+			t.Errorf("%s: score = %v, want 10", key, got)
+		}
+		if got := server.TTL(key); got != wantTTL {
+			t.Errorf("%s: TTL = %s, want %s", key, got, wantTTL)
+		}
+	}
+}
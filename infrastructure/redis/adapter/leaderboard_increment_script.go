@@ -0,0 +1,45 @@
+package adapter
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+// leaderboardIncrementScript applies one leaderboard entry to all of its boards.
+//
+// Redis does not roll back a script that fails halfway, so every board is type-checked before any is changed, and
+// the dedupe marker is only set once all boards are updated; an entry that failed is applied again on retry.
+//
+// KEYS[1]    dedupe marker; always a key in the boards' slot so the call never spans slots in cluster mode
+// KEYS[2..]  boards
+// ARGV[1]    member
+// ARGV[2]    increment
+// ARGV[3]    dedupe marker TTL in seconds, 0 when the entry has no ID and is not deduplicated
+// ARGV[4..]  TTL in seconds per board, 0 for none; only set when the board has no TTL yet
+//
+// Returns 0 when the entry was already applied, 1 otherwise.
+var leaderboardIncrementScript = redis.NewScript(`
+local dedupe = tonumber(ARGV[3]) > 0
+if dedupe and redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+
+for i = 2, #KEYS do
+	local keyType = redis.call("TYPE", KEYS[i]).ok
+	if keyType ~= "zset" and keyType ~= "none" then
+		return redis.error_reply("WRONGTYPE board " .. KEYS[i] .. " is a " .. keyType .. ", not a sorted set")
+	end
+end
+
+for i = 2, #KEYS do
+	redis.call("ZINCRBY", KEYS[i], ARGV[2], ARGV[1])
+	local ttl = tonumber(ARGV[i + 2])
+	if ttl > 0 and redis.call("TTL", KEYS[i]) == -1 then
+		redis.call("EXPIRE", KEYS[i], ttl)
+	end
+end
+
+if dedupe then
+	redis.call("SET", KEYS[1], "1", "EX", ARGV[3])
+end
+return 1
+`)
@@ -0,0 +1,158 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"pomocore-data/domains/leaderboard/domain"
+	"pomocore-data/shared/common/logger"
+)
+
+type LeaderboardKeyMigrationMode string
+
+const (
+	// MigrationModeCopy replaces the v2 board with the v1 board; use it once dual-write is on, since v1 then holds everything
+	MigrationModeCopy LeaderboardKeyMigrationMode = "copy"
+	// MigrationModeMerge adds the v1 scores to the v2 board; use it only if v2 boards were written without dual-write
+	MigrationModeMerge LeaderboardKeyMigrationMode = "merge"
+	// MigrationModeDelete removes v1 boards once nothing writes or reads them anymore
+	MigrationModeDelete LeaderboardKeyMigrationMode = "delete"
+)
+
+type LeaderboardKeyMigrationOptions struct {
+	Mode      LeaderboardKeyMigrationMode
+	DryRun    bool
+	ScanCount int64
+}
+
+type LeaderboardKeyMigrationResult struct {
+	Scanned  int
+	Migrated int
+	Deleted  int
+}
+
+// leaderboardMergeScript adds the scores of the source board KEYS[2] to the target board KEYS[1]. ZUNIONSTORE drops
+// the target's TTL, so the longer of the two boards' remaining TTLs is set again; the merged board only stays
+// without a TTL if one of the boards had none.
+var leaderboardMergeScript = redis.NewScript(`
+local ttl = 0
+for i = 1, 2 do
+	local pttl = redis.call("PTTL", KEYS[i])
+	if pttl == -1 then
+		ttl = -1
+		break
+	end
+	ttl = math.max(ttl, pttl)
+end
+
+redis.call("ZUNIONSTORE", KEYS[1], 2, KEYS[1], KEYS[2])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1
+`)
+
+// LeaderboardKeyMigrator moves weekly and monthly boards from the v1 key scheme to v2
+type LeaderboardKeyMigrator struct {
+	client    redis.UniversalClient
+	keyLayout domain.KeyLayout
+}
+
+func NewLeaderboardKeyMigrator(client redis.UniversalClient, keyLayout domain.KeyLayout) *LeaderboardKeyMigrator {
+	return &LeaderboardKeyMigrator{client: client, keyLayout: keyLayout}
+}
+
+func (m *LeaderboardKeyMigrator) Migrate(ctx context.Context, opts LeaderboardKeyMigrationOptions) (LeaderboardKeyMigrationResult, error) {
+	var result LeaderboardKeyMigrationResult
+	if opts.Mode != MigrationModeCopy && opts.Mode != MigrationModeMerge && opts.Mode != MigrationModeDelete {
+		return result, fmt.Errorf("unknown migration mode %q", opts.Mode)
+	}
+
+	// Keys are migrated one at a time even when a cluster is scanned concurrently per master
+	var mu sync.Mutex
+	err := m.scan(ctx, m.keyLayout.Prefix()+":*", opts.ScanCount, func(source string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Scanned++
+
+		target, ok := domain.UpgradeLeaderboardKey(source)
+		if !ok {
+			return nil
+		}
+
+		keyType, err := m.client.Type(ctx, source).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read type of %s: %w", source, err)
+		}
+		if keyType != "zset" {
+			return nil
+		}
+
+		logger.Info("Migrating leaderboard key",
+			zap.String("source", source),
+			zap.String("target", target),
+			zap.String("mode", string(opts.Mode)),
+			zap.Bool("dry_run", opts.DryRun))
+		if opts.DryRun {
+			return nil
+		}
+
+		if opts.Mode == MigrationModeDelete {
+			if err := m.client.Del(ctx, source).Err(); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", source, err)
+			}
+			result.Deleted++
+			return nil
+		}
+
+		if err := m.migrateKey(ctx, source, target, opts.Mode); err != nil {
+			return fmt.Errorf("failed to migrate %s to %s: %w", source, target, err)
+		}
+		result.Migrated++
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (m *LeaderboardKeyMigrator) migrateKey(ctx context.Context, source, target string, mode LeaderboardKeyMigrationMode) error {
+	switch mode {
+	case MigrationModeCopy:
+		// COPY carries the source's TTL over to the target
+		return m.client.Copy(ctx, source, target, 0, true).Err()
+	case MigrationModeMerge:
+		return leaderboardMergeScript.Run(ctx, m.client, []string{target, source}).Err()
+	default:
+		return fmt.Errorf("unknown migration mode %q", mode)
+	}
+}
+
+// scan calls fn for every key matching the pattern; a cluster is scanned on each master since SCAN only covers one node
+func (m *LeaderboardKeyMigrator) scan(ctx context.Context, pattern string, count int64, fn func(key string) error) error {
+	scanNode := func(ctx context.Context, client redis.UniversalClient) error {
+		iter := client.Scan(ctx, 0, pattern, count).Iterator()
+		for iter.Next(ctx) {
+			if err := fn(iter.Val()); err != nil {
+				return err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("failed to scan leaderboard keys: %w", err)
+		}
+		return nil
+	}
+
+	if cluster, ok := m.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scanNode(ctx, node)
+		})
+	}
+	return scanNode(ctx, m.client)
+}
@@ -0,0 +1,97 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"pomocore-data/domains/leaderboard/domain"
+	"pomocore-data/shared/common/logger"
+)
+
+func TestLeaderboardKeyMigratorKeepsScoresAndTTL(t *testing.T) {
+	const source, target = "leaderboard:{lb}:work:2025-W5", "leaderboard:{lb}:work:2025-W05"
+
+	tests := []struct {
+		name       string
+		mode       LeaderboardKeyMigrationMode
+		targetTTL  time.Duration
+		wantScores map[string]float64
+		wantTTL    time.Duration
+	}{
+		{
+			name:       "copy replaces the target with the source and its TTL",
+			mode:       MigrationModeCopy,
+			targetTTL:  2 * time.Hour,
+			wantScores: map[string]float64{"a": 10, "b": 5},
+			wantTTL:    time.Hour,
+		},
+		{
+			name:       "merge keeps the target's longer TTL",
+			mode:       MigrationModeMerge,
+			targetTTL:  2 * time.Hour,
+			wantScores: map[string]float64{"a": 11, "b": 5, "c": 3},
+			wantTTL:    2 * time.Hour,
+		},
+		{
+			name:       "merge takes the source's longer TTL",
+			mode:       MigrationModeMerge,
+			targetTTL:  time.Minute,
+			wantScores: map[string]float64{"a": 11, "b": 5, "c": 3},
+			wantTTL:    time.Hour,
+		},
+		{
+			name:       "merge keeps a board without TTL persistent",
+			mode:       MigrationModeMerge,
+			wantScores: map[string]float64{"a": 11, "b": 5, "c": 3},
+		},
+	}
+
+	logger.Logger = zap.NewNop()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { _ = client.Close() })
+			ctx := context.Background()
+
+			client.ZAdd(ctx, source, redis.Z{Score: 10, Member: "a"}, redis.Z{Score: 5, Member: "b"})
+			client.Expire(ctx, source, time.Hour)
+			client.ZAdd(ctx, target, redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 3, Member: "c"})
+			if tt.targetTTL > 0 {
+				client.Expire(ctx, target, tt.targetTTL)
+			}
+
+			migrator := NewLeaderboardKeyMigrator(client, domain.KeyLayout{Schemes: []domain.KeyScheme{domain.KeySchemeV1}, HashTag: "lb"})
+			result, err := migrator.Migrate(ctx, LeaderboardKeyMigrationOptions{Mode: tt.mode, ScanCount: 100})
+			if err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			if result.Migrated != 1 {
+				t.Errorf("migrated %d keys, want 1", result.Migrated)
+			}
+
+			members := client.ZRangeWithScores(ctx, target, 0, -1).Val()
+			got := make(map[string]float64, len(members))
+			for _, member := range members {
+				got[member.Member.(string)] = member.Score
+			}
+			if len(got) != len(tt.wantScores) {
+				t.Errorf("scores = %v, want %v", got, tt.wantScores)
+			}
+			for member, score := range tt.wantScores {
+				if got[member] != score {
+					t.Errorf("scores = %v, want %v", got, tt.wantScores)
+					break
+				}
+			}
+			if ttl := server.TTL(target); ttl != tt.wantTTL {
+				t.Errorf("TTL = %s, want %s", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package adapter
 
 import (
+	"context"
+
 	"pomocore-data/domains/patternClassifier/domain/core"
 )
 
@@ -14,6 +16,6 @@ func NewPatternClassifierAdapter(classifier *core.PatternClassifier) *PatternCla
 	}
 }
 
-func (p *PatternClassifierAdapter) Classify(app, title, url string) (string, bool) {
-	return p.classifier.Classify(app, title, url)
+func (p *PatternClassifierAdapter) Classify(ctx context.Context, app, title, url string) (string, bool) {
+	return p.classifier.Classify(ctx, app, title, url)
 }
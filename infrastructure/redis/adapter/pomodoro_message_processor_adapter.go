@@ -8,23 +8,25 @@ import (
 
 	"pomocore-data/domains/message"
 	pomodoroUseCase "pomocore-data/domains/pomodoro/application/usecase"
-	"pomocore-data/infrastructure/redis/config"
 	"pomocore-data/infrastructure/redis/consumer"
 	"pomocore-data/shared/common/logger"
 )
 
 type PomodoroMessageProcessorAdapter struct {
-	classifyUseCase pomodoroUseCase.ClassifyPomodoroUseCase
-	redisClient     *redis.Client
+	classifyUseCase          pomodoroUseCase.ClassifyPomodoroUseCase
+	sessionCompletionUseCase pomodoroUseCase.SessionCompletionUseCase
+	deadLetters              *consumer.DeadLetterQueue
 }
 
 func NewPomodoroMessageProcessorAdapter(
 	classifyUseCase pomodoroUseCase.ClassifyPomodoroUseCase,
-	redisClient *redis.Client,
+	sessionCompletionUseCase pomodoroUseCase.SessionCompletionUseCase,
+	deadLetters *consumer.DeadLetterQueue,
 ) consumer.MessageProcessor {
 	return &PomodoroMessageProcessorAdapter{
-		classifyUseCase: classifyUseCase,
-		redisClient:     redisClient,
+		classifyUseCase:          classifyUseCase,
+		sessionCompletionUseCase: sessionCompletionUseCase,
+		deadLetters:              deadLetters,
 	}
 }
 
@@ -34,7 +36,7 @@ func (a *PomodoroMessageProcessorAdapter) ProcessBatch(ctx context.Context, mess
 	}
 
 	// Parse messages from Redis format
-	pomodoroMsgs, err := a.parseMessages(messages)
+	pomodoroMsgs, err := a.parseMessages(ctx, messages)
 	if err != nil {
 		return fmt.Errorf("failed to parse messages: %w", err)
 	}
@@ -44,14 +46,15 @@ func (a *PomodoroMessageProcessorAdapter) ProcessBatch(ctx context.Context, mess
 	}
 
 	// Process messages through use case
+	// Results that could not be stored fail the batch so its messages stay pending and are retried
 	_, sessionScoreMessages, err := a.classifyUseCase.Execute(ctx, pomodoroMsgs)
 	if err != nil {
-		// Log but continue - we want to acknowledge messages even if processing partially fails
-		logger.Error("Error processing pomodoro messages", logger.WithError(err))
+		return fmt.Errorf("failed to process pomodoro messages: %w", err)
 	}
 
-	// Publish session score events
-	if err := a.publishSessionScoreEvents(ctx, sessionScoreMessages); err != nil {
+	// Publish session score events right away when their sessions are already fully categorized;
+	// the outbox relay picks up the rest
+	if err := a.sessionCompletionUseCase.CompleteSessions(ctx, sessionScoreMessages); err != nil {
 		logger.Error("Error publishing session score events", logger.WithError(err))
 	}
 
@@ -59,14 +62,29 @@ func (a *PomodoroMessageProcessorAdapter) ProcessBatch(ctx context.Context, mess
 	return nil
 }
 
-func (a *PomodoroMessageProcessorAdapter) parseMessages(messages []redis.XMessage) ([]*message.PomodoroPatternClassifyMessage, error) {
+// PartitionKey routes every message of a user to the same worker so a user's session is processed in stream order
+func (a *PomodoroMessageProcessorAdapter) PartitionKey(msg redis.XMessage) string {
+	if userID, ok := msg.Values["userId"].(string); ok && userID != "" {
+		return userID
+	}
+	if userID := message.PayloadUserID(msg.Values); userID != "" {
+		return userID
+	}
+	return msg.ID
+}
+
+// parseMessages returns the valid messages and routes invalid ones to the dead letter queue.
+// If one cannot be sent there, the batch fails so the message stays pending instead of being acknowledged.
+func (a *PomodoroMessageProcessorAdapter) parseMessages(ctx context.Context, messages []redis.XMessage) ([]*message.PomodoroPatternClassifyMessage, error) {
 	var pomodoroMsgs []*message.PomodoroPatternClassifyMessage
 
 	for _, msg := range messages {
 		pomodoroMsg, err := message.ParseFromRedisValues(msg.Values)
 		if err != nil {
-			logger.Warn("Error parsing message", zap.String("message_id", msg.ID), logger.WithError(err))
-			// Skip invalid messages but continue processing
+			logger.Warn("Rejecting invalid message", zap.String("message_id", msg.ID), logger.WithError(err))
+			if dlqErr := a.deadLetters.Send(ctx, consumer.SourceStream(ctx), msg, err); dlqErr != nil {
+				return nil, dlqErr
+			}
 			continue
 		}
 		pomodoroMsgs = append(pomodoroMsgs, pomodoroMsg)
@@ -74,27 +92,3 @@ func (a *PomodoroMessageProcessorAdapter) parseMessages(messages []redis.XMessag
 
 	return pomodoroMsgs, nil
 }
-
-func (a *PomodoroMessageProcessorAdapter) publishSessionScoreEvents(ctx context.Context, sessionScoreMessages []*message.SessionScoreMessage) error {
-	for _, sessionScoreMsg := range sessionScoreMessages {
-		_, err := a.redisClient.XAdd(ctx, &redis.XAddArgs{
-			Stream: config.SessionScoreSave.StreamKey,
-			Values: sessionScoreMsg.ToRedisValues(),
-		}).Result()
-
-		if err != nil {
-			logger.Error("Error sending sessionScore message",
-				zap.String("user_id", sessionScoreMsg.UserID),
-				zap.Int("session", sessionScoreMsg.Session),
-				logger.WithError(err))
-			continue
-		}
-
-		logger.Info("Classification Commited",
-			zap.String("user_id", sessionScoreMsg.UserID),
-			zap.String("session_date", sessionScoreMsg.SessionDate.Format("2006-01-02")),
-			zap.Int("session", sessionScoreMsg.Session))
-	}
-
-	return nil
-}
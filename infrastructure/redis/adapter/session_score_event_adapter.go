@@ -0,0 +1,34 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"pomocore-data/domains/message"
+	pomodoroPort "pomocore-data/domains/pomodoro/application/port"
+)
+
+type SessionScoreEventAdapter struct {
+	client    redis.UniversalClient
+	streamKey string
+}
+
+func NewSessionScoreEventPort(client redis.UniversalClient, streamKey string) pomodoroPort.SessionScoreEventPort {
+	return &SessionScoreEventAdapter{
+		client:    client,
+		streamKey: streamKey,
+	}
+}
+
+func (a *SessionScoreEventAdapter) Publish(ctx context.Context, msg *message.SessionScoreMessage) error {
+	_, err := a.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: a.streamKey,
+		Values: msg.ToRedisValues(),
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to publish session score event: %w", err)
+	}
+	return nil
+}
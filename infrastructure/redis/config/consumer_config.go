@@ -0,0 +1,241 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	envConfig "pomocore-data/shared/common/config"
+)
+
+const (
+	PatternMatchPipeline = "pattern_match"
+	SessionScorePipeline = "session_score"
+)
+
+// Streams holds the default stream definitions for each known pipeline name
+var Streams = map[string]StreamInfo{
+	PatternMatchPipeline: PomodoroPatternMatch,
+	SessionScorePipeline: SessionScoreSave,
+}
+
+type ConsumerConfig struct {
+	Name         string        `yaml:"-"`
+	Stream       StreamInfo    `yaml:"stream"`
+	BatchSize    int           `yaml:"batchSize"`
+	Workers      int           `yaml:"workers"`
+	BlockTime    time.Duration `yaml:"blockTime"`
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+
+	// BatchSize and Workers are the starting point; the consumer adapts both within these bounds
+	MinBatchSize       int           `yaml:"minBatchSize"`
+	MaxBatchSize       int           `yaml:"maxBatchSize"`
+	MinWorkers         int           `yaml:"minWorkers"`
+	MaxWorkers         int           `yaml:"maxWorkers"`
+	TargetBatchLatency time.Duration `yaml:"targetBatchLatency"`
+
+	// Reads pause with a doubling backoff while the last BackpressureWindow batches fail or run slow
+	BackpressureWindow         int           `yaml:"backpressureWindow"`
+	BackpressureErrorPercent   int           `yaml:"backpressureErrorPercent"`
+	BackpressureLatency        time.Duration `yaml:"backpressureLatency"`
+	BackpressureInitialBackoff time.Duration `yaml:"backpressureInitialBackoff"`
+	BackpressureMaxBackoff     time.Duration `yaml:"backpressureMaxBackoff"`
+
+	// MaxDeliveries is how often a failing message is tried before it moves to the dead letter queue
+	MaxDeliveries int `yaml:"maxDeliveries"`
+}
+
+// newConsumerConfig returns the tuning defaults; zero bounds and drain timeout are derived in StreamsConfig.Resolve
+func newConsumerConfig(stream StreamInfo) ConsumerConfig {
+	return ConsumerConfig{
+		Stream:                     stream,
+		BatchSize:                  50,
+		Workers:                    10,
+		BlockTime:                  2 * time.Second,
+		TargetBatchLatency:         10 * time.Second,
+		BackpressureWindow:         10,
+		BackpressureErrorPercent:   50,
+		BackpressureLatency:        time.Minute,
+		BackpressureInitialBackoff: time.Second,
+		BackpressureMaxBackoff:     time.Minute,
+		MaxDeliveries:              5,
+	}
+}
+
+// UnmarshalYAML starts from the defaults so a config file only needs the settings it changes
+func (c *ConsumerConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain ConsumerConfig
+	*c = newConsumerConfig(StreamInfo{})
+	return node.Decode((*plain)(c))
+}
+
+// applyEnv reads CONSUMER_<NAME>_* variables, e.g. CONSUMER_PATTERN_MATCH_WORKERS
+func (c *ConsumerConfig) applyEnv(env *envConfig.Overrides) {
+	prefix := "CONSUMER_" + strings.ToUpper(c.Name) + "_"
+	env.String(prefix+"STREAM_KEY", &c.Stream.StreamKey)
+	env.String(prefix+"GROUP", &c.Stream.Group)
+	env.String(prefix+"CONSUMER", &c.Stream.Consumer)
+	env.Int(prefix+"BATCH_SIZE", &c.BatchSize)
+	env.Int(prefix+"WORKERS", &c.Workers)
+	env.Duration(prefix+"BLOCK_TIME", &c.BlockTime)
+	env.Duration(prefix+"DRAIN_TIMEOUT", &c.DrainTimeout)
+	env.Int(prefix+"MIN_BATCH_SIZE", &c.MinBatchSize)
+	env.Int(prefix+"MAX_BATCH_SIZE", &c.MaxBatchSize)
+	env.Int(prefix+"MIN_WORKERS", &c.MinWorkers)
+	env.Int(prefix+"MAX_WORKERS", &c.MaxWorkers)
+	env.Duration(prefix+"TARGET_BATCH_LATENCY", &c.TargetBatchLatency)
+	env.Int(prefix+"BACKPRESSURE_WINDOW", &c.BackpressureWindow)
+	env.Int(prefix+"BACKPRESSURE_ERROR_PERCENT", &c.BackpressureErrorPercent)
+	env.Duration(prefix+"BACKPRESSURE_LATENCY", &c.BackpressureLatency)
+	env.Duration(prefix+"BACKPRESSURE_INITIAL_BACKOFF", &c.BackpressureInitialBackoff)
+	env.Duration(prefix+"BACKPRESSURE_MAX_BACKOFF", &c.BackpressureMaxBackoff)
+	env.Int(prefix+"MAX_DELIVERIES", &c.MaxDeliveries)
+}
+
+func (c *ConsumerConfig) Validate() error {
+	if c.Stream.StreamKey == "" || c.Stream.Group == "" || c.Stream.Consumer == "" {
+		return fmt.Errorf("consumer %q: stream key, group and consumer name are required", c.Name)
+	}
+	if c.BatchSize <= 0 || c.Workers <= 0 {
+		return fmt.Errorf("consumer %q: batch size and workers must be positive", c.Name)
+	}
+	if c.DrainTimeout <= 0 {
+		return fmt.Errorf("consumer %q: drain timeout must be positive", c.Name)
+	}
+	if c.MinBatchSize <= 0 || c.MinBatchSize > c.MaxBatchSize {
+		return fmt.Errorf("consumer %q: batch size bounds must satisfy 0 < min <= max, got %d..%d", c.Name, c.MinBatchSize, c.MaxBatchSize)
+	}
+	if c.MinWorkers <= 0 || c.MinWorkers > c.MaxWorkers {
+		return fmt.Errorf("consumer %q: worker bounds must satisfy 0 < min <= max, got %d..%d", c.Name, c.MinWorkers, c.MaxWorkers)
+	}
+	if c.BackpressureErrorPercent < 0 || c.BackpressureErrorPercent > 100 {
+		return fmt.Errorf("consumer %q: backpressure error percent must be between 0 and 100, got %d", c.Name, c.BackpressureErrorPercent)
+	}
+	if c.BackpressureWindow > 0 && (c.BackpressureInitialBackoff <= 0 || c.BackpressureInitialBackoff > c.BackpressureMaxBackoff) {
+		return fmt.Errorf("consumer %q: backpressure backoff must satisfy 0 < initial <= max, got %s..%s", c.Name, c.BackpressureInitialBackoff, c.BackpressureMaxBackoff)
+	}
+	if c.MaxDeliveries <= 0 {
+		return fmt.Errorf("consumer %q: max deliveries must be positive, got %d", c.Name, c.MaxDeliveries)
+	}
+	return nil
+}
+
+type DeadLetterConfig struct {
+	StreamKey string `yaml:"streamKey"`
+	MaxLen    int64  `yaml:"maxLen"`
+}
+
+// StreamsConfig selects the pipelines to consume and names the streams the service writes to
+type StreamsConfig struct {
+	// Enabled lists the pipelines to run, each tuned by its entry in Consumers
+	Enabled []string `yaml:"enabled"`
+	// DrainTimeout applies to every consumer that does not set its own
+	DrainTimeout          time.Duration             `yaml:"drainTimeout"`
+	Consumers             map[string]ConsumerConfig `yaml:"consumers"`
+	DeadLetter            DeadLetterConfig          `yaml:"deadLetter"`
+	SessionScoreStreamKey string                    `yaml:"sessionScoreStreamKey"`
+}
+
+// NewStreamsConfig returns the defaults; consumer entries are only needed for settings that differ from them
+func NewStreamsConfig() StreamsConfig {
+	return StreamsConfig{
+		Enabled:      []string{PatternMatchPipeline},
+		DrainTimeout: 30 * time.Second,
+		Consumers:    make(map[string]ConsumerConfig),
+		DeadLetter: DeadLetterConfig{
+			StreamKey: PomodoroPatternMatchDeadLetter.StreamKey,
+			MaxLen:    100000,
+		},
+		SessionScoreStreamKey: SessionScoreSave.StreamKey,
+	}
+}
+
+func (s *StreamsConfig) ApplyEnv(env *envConfig.Overrides) {
+	env.Strings("STREAM_CONSUMERS", &s.Enabled)
+	env.Duration("CONSUMER_DRAIN_TIMEOUT", &s.DrainTimeout)
+	env.String("DLQ_STREAM_KEY", &s.DeadLetter.StreamKey)
+	env.Int64("DLQ_MAX_LEN", &s.DeadLetter.MaxLen)
+	env.String("SESSION_SCORE_STREAM_KEY", &s.SessionScoreStreamKey)
+
+	s.ensureEnabled()
+	for _, name := range s.Enabled {
+		c := s.Consumers[name]
+		c.applyEnv(env)
+		s.Consumers[name] = c
+	}
+}
+
+// ensureEnabled drops blank and repeated names and gives every enabled pipeline an entry
+func (s *StreamsConfig) ensureEnabled() {
+	enabled := make([]string, 0, len(s.Enabled))
+	for _, name := range s.Enabled {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(enabled, name) {
+			enabled = append(enabled, name)
+		}
+	}
+	s.Enabled = enabled
+
+	if s.Consumers == nil {
+		s.Consumers = make(map[string]ConsumerConfig)
+	}
+	for _, name := range s.Enabled {
+		c, ok := s.Consumers[name]
+		if !ok {
+			c = newConsumerConfig(Streams[name])
+		}
+		c.Name = name
+		s.Consumers[name] = c
+	}
+}
+
+// Resolve fills settings derived from others: empty stream names fall back to the pipeline's defaults,
+// unset bounds follow BatchSize and Workers and an unset drain timeout follows the shared one
+func (s *StreamsConfig) Resolve() {
+	s.ensureEnabled()
+	for _, name := range s.Enabled {
+		c := s.Consumers[name]
+		defaults := Streams[name]
+		c.Stream.StreamKey = cmp.Or(c.Stream.StreamKey, defaults.StreamKey)
+		c.Stream.Group = cmp.Or(c.Stream.Group, defaults.Group)
+		c.Stream.Consumer = cmp.Or(c.Stream.Consumer, defaults.Consumer)
+		c.DrainTimeout = cmp.Or(c.DrainTimeout, s.DrainTimeout)
+		c.MinBatchSize = cmp.Or(c.MinBatchSize, min(10, c.BatchSize))
+		c.MaxBatchSize = cmp.Or(c.MaxBatchSize, max(200, c.BatchSize))
+		c.MinWorkers = cmp.Or(c.MinWorkers, min(2, c.Workers))
+		c.MaxWorkers = cmp.Or(c.MaxWorkers, max(20, c.Workers))
+		s.Consumers[name] = c
+	}
+}
+
+func (s *StreamsConfig) Validate() error {
+	if len(s.Enabled) == 0 {
+		return fmt.Errorf("no stream consumers enabled (STREAM_CONSUMERS)")
+	}
+	var errs []error
+	for _, c := range s.EnabledConsumers() {
+		if err := c.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.DeadLetter.StreamKey == "" {
+		errs = append(errs, fmt.Errorf("dead letter stream key (DLQ_STREAM_KEY) is required"))
+	}
+	if s.SessionScoreStreamKey == "" {
+		errs = append(errs, fmt.Errorf("session score stream key (SESSION_SCORE_STREAM_KEY) is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// EnabledConsumers returns the settings of every enabled pipeline, in the order they were listed
+func (s *StreamsConfig) EnabledConsumers() []ConsumerConfig {
+	configs := make([]ConsumerConfig, 0, len(s.Enabled))
+	for _, name := range s.Enabled {
+		configs = append(configs, s.Consumers[name])
+	}
+	return configs
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	envConfig "pomocore-data/shared/common/config"
+)
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+type RedisConfig struct {
+	Mode     string   `yaml:"mode"`
+	Addrs    []string `yaml:"addrs"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password" secret:"true"`
+	DB       int      `yaml:"db"`
+
+	// Sentinel mode only
+	MasterName       string `yaml:"sentinelMaster"`
+	SentinelUsername string `yaml:"sentinelUsername"`
+	SentinelPassword string `yaml:"sentinelPassword" secret:"true"`
+
+	TLSEnabled            bool   `yaml:"tls"`
+	TLSCAFile             string `yaml:"tlsCAFile"`
+	TLSCertFile           string `yaml:"tlsCertFile"`
+	TLSKeyFile            string `yaml:"tlsKeyFile"`
+	TLSServerName         string `yaml:"tlsServerName"`
+	TLSInsecureSkipVerify bool   `yaml:"tlsInsecureSkipVerify"`
+}
+
+// NewRedisConfig returns the defaults, to be overridden by the config file and ApplyEnv
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		Mode:  RedisModeStandalone,
+		Addrs: []string{"localhost:6379"},
+	}
+}
+
+// ApplyEnv reads the connection settings; REDIS_ADDRS lists sentinel or cluster seed nodes and takes precedence over REDIS_ADDR
+func (c *RedisConfig) ApplyEnv(env *envConfig.Overrides) {
+	env.String("REDIS_MODE", &c.Mode)
+	env.Strings("REDIS_ADDR", &c.Addrs)
+	env.Strings("REDIS_ADDRS", &c.Addrs)
+	env.String("REDIS_USERNAME", &c.Username)
+	env.String("REDIS_PASSWORD", &c.Password)
+	env.Int("REDIS_DB", &c.DB)
+	env.String("REDIS_SENTINEL_MASTER", &c.MasterName)
+	env.String("REDIS_SENTINEL_USERNAME", &c.SentinelUsername)
+	env.String("REDIS_SENTINEL_PASSWORD", &c.SentinelPassword)
+	env.Bool("REDIS_TLS", &c.TLSEnabled)
+	env.String("REDIS_TLS_CA_FILE", &c.TLSCAFile)
+	env.String("REDIS_TLS_CERT_FILE", &c.TLSCertFile)
+	env.String("REDIS_TLS_KEY_FILE", &c.TLSKeyFile)
+	env.String("REDIS_TLS_SERVER_NAME", &c.TLSServerName)
+	env.Bool("REDIS_TLS_INSECURE_SKIP_VERIFY", &c.TLSInsecureSkipVerify)
+}
+
+func (c *RedisConfig) Validate() error {
+	if len(c.Addrs) == 0 {
+		return fmt.Errorf("at least one redis address is required")
+	}
+	switch c.Mode {
+	case RedisModeStandalone:
+		if len(c.Addrs) > 1 {
+			return fmt.Errorf("standalone mode takes a single address, got %d", len(c.Addrs))
+		}
+	case RedisModeSentinel:
+		if c.MasterName == "" {
+			return fmt.Errorf("sentinel mode requires REDIS_SENTINEL_MASTER")
+		}
+	case RedisModeCluster:
+		if c.DB != 0 {
+			return fmt.Errorf("cluster mode only supports DB 0")
+		}
+	default:
+		return fmt.Errorf("unknown redis mode %q", c.Mode)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("REDIS_TLS_CERT_FILE and REDIS_TLS_KEY_FILE must be set together")
+	}
+	return nil
+}
+
+func (c *RedisConfig) tlsConfig() (*tls.Config, error) {
+	if !c.TLSEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+	if c.TLSCAFile != "" {
+		ca, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", c.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// NewClient builds a client for the configured mode without connecting
+func (c *RedisConfig) NewClient() (redis.UniversalClient, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            c.Addrs,
+		Username:         c.Username,
+		Password:         c.Password,
+		DB:               c.DB,
+		MasterName:       c.MasterName,
+		SentinelUsername: c.SentinelUsername,
+		SentinelPassword: c.SentinelPassword,
+		TLSConfig:        tlsConfig,
+	}
+
+	switch c.Mode {
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return redis.NewClient(opts.Simple()), nil
+	}
+}
+
+func ConnectRedis(config *RedisConfig) (redis.UniversalClient, error) {
+	client, err := config.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// DefaultLeaderboardHashTag keeps all leaderboard keys in one slot in cluster mode, where the update script needs it
+func (c *RedisConfig) DefaultLeaderboardHashTag() string {
+	if c.Mode == RedisModeCluster {
+		return "lb"
+	}
+	return ""
+}
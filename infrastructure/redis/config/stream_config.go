@@ -1,9 +1,9 @@
 package config
 
 type StreamInfo struct {
-	StreamKey string
-	Group     string
-	Consumer  string
+	StreamKey string `yaml:"streamKey"`
+	Group     string `yaml:"group"`
+	Consumer  string `yaml:"consumer"`
 }
 
 var (
@@ -13,6 +13,11 @@ var (
 		Consumer:  "pattern_match_consumer",
 	}
 
+	// PomodoroPatternMatchDeadLetter receives pattern-match messages that failed validation
+	PomodoroPatternMatchDeadLetter = StreamInfo{
+		StreamKey: "pattern_match_dlq",
+	}
+
 	SessionScoreSave = StreamInfo{
 		StreamKey: "session_score_stream",
 		Group:     "session_score_group",
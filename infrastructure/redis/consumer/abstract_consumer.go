@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+	"pomocore-data/shared/common/tracing"
 )
 
 type StreamConfig struct {
@@ -23,36 +30,103 @@ type MessageProcessor interface {
 	ProcessBatch(ctx context.Context, messages []redis.XMessage) error
 }
 
+// Partitioner is implemented by processors that need messages with the same key processed in stream order.
+// Such messages are always routed to the same worker; other processors share one queue across all workers.
+type Partitioner interface {
+	PartitionKey(msg redis.XMessage) string
+}
+
+type sourceStreamKey struct{}
+
+// SourceStream returns the stream the batch being processed was read from, e.g. to label dead letters
+func SourceStream(ctx context.Context) string {
+	stream, _ := ctx.Value(sourceStreamKey{}).(string)
+	return stream
+}
+
+const (
+	ackTimeout = 5 * time.Second
+	// inFlightPollInterval is how often the read loop checks whether dispatched batches have finished
+	inFlightPollInterval = 100 * time.Millisecond
+)
+
+// Stats holds counters collected since the consumer was created
+type Stats struct {
+	BatchesProcessed  int64
+	MessagesProcessed int64
+	BatchErrors       int64
+	AckErrors         int64
+	AbandonedBatches  int64
+	DeadLettered      int64
+}
+
 type AbstractConsumer struct {
-	client     *redis.Client
-	config     StreamConfig
-	processor  MessageProcessor
-	workerPool int
-	batchSize  int
-	blockTime  time.Duration
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
+	client       redis.UniversalClient
+	config       StreamConfig
+	processor    MessageProcessor
+	workerPool   int
+	scaler       *autoscaler
+	backpressure *backpressure
+	blockTime    time.Duration
+	drainTimeout time.Duration
+	// Pending entries read more than maxDeliveries times are moved to deadLetters instead of retried again
+	deadLetters   *DeadLetterQueue
+	maxDeliveries int64
+
+	// ctx stops reading new messages; processCtx is only cancelled once the drain deadline passes
+	ctx           context.Context
+	cancel        context.CancelFunc
+	processCtx    context.Context
+	processCancel context.CancelFunc
+
+	consumeWg         sync.WaitGroup
+	workerWg          sync.WaitGroup
+	batchesProcessed  atomic.Int64
+	messagesProcessed atomic.Int64
+	batchErrors       atomic.Int64
+	ackErrors         atomic.Int64
+	abandonedBatches  atomic.Int64
+	deadLettered      atomic.Int64
+	// lastRead is the unix nano time the read loop last returned from the stream
+	lastRead atomic.Int64
+	// inFlight counts batches handed to workers and not finished yet
+	inFlight atomic.Int64
+	// claims counts takeovers of a dead consumer's pending entries, which the read loop then reads like failures
+	claims atomic.Int64
+	// retriedUpTo is the failure count when the read loop last found its pending list empty; read loop only
+	retriedUpTo int64
 }
 
 func NewAbstractConsumer(
-	client *redis.Client,
+	client redis.UniversalClient,
 	config StreamConfig,
 	processor MessageProcessor,
 	workerPool int,
 	batchSize int,
+	scaling ScalingConfig,
+	backpressure BackpressureConfig,
+	deadLetters *DeadLetterQueue,
+	maxDeliveries int,
 	blockTime time.Duration,
+	drainTimeout time.Duration,
 ) *AbstractConsumer {
 	ctx, cancel := context.WithCancel(context.Background())
+	processCtx, processCancel := context.WithCancel(context.Background())
 	return &AbstractConsumer{
-		client:     client,
-		config:     config,
-		processor:  processor,
-		workerPool: workerPool,
-		batchSize:  batchSize,
-		blockTime:  blockTime,
-		ctx:        ctx,
-		cancel:     cancel,
+		client:        client,
+		config:        config,
+		processor:     processor,
+		workerPool:    scaling.MaxWorkers,
+		scaler:        newAutoscaler(config.StreamKey, scaling, batchSize, workerPool),
+		backpressure:  newBackpressure(config.StreamKey, backpressure),
+		blockTime:     blockTime,
+		drainTimeout:  drainTimeout,
+		deadLetters:   deadLetters,
+		maxDeliveries: int64(maxDeliveries),
+		ctx:           ctx,
+		cancel:        cancel,
+		processCtx:    processCtx,
+		processCancel: processCancel,
 	}
 }
 
@@ -62,27 +136,82 @@ func (c *AbstractConsumer) Start() error {
 		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
-	batchChan := make(chan []redis.XMessage, c.workerPool*2)
-
+	batchChans := c.newBatchChannels()
 	for i := 0; i < c.workerPool; i++ {
-		c.wg.Add(1)
-		go c.batchWorker(i, batchChan)
+		c.workerWg.Add(1)
+		go c.batchWorker(i, batchChans[i%len(batchChans)])
 	}
 
-	c.wg.Add(1)
-	go c.consume(batchChan)
+	c.lastRead.Store(time.Now().UnixNano())
+	c.consumeWg.Add(2)
+	go c.consume(batchChans)
+	go c.maintainMembership()
 
+	// Close the batch channels only after every producer of batches has exited
+	go func() {
+		c.consumeWg.Wait()
+		for _, ch := range batchChans {
+			close(ch)
+		}
+	}()
+
+	_, activeWorkers := c.scaler.workers.state()
 	logger.Info("Consumer started",
 		zap.String("stream", c.config.StreamKey),
-		zap.Int("workers", c.workerPool))
+		zap.String("consumer", c.config.Consumer),
+		zap.Int("workers", activeWorkers),
+		zap.Int("max_workers", c.workerPool),
+		zap.Int("batch_size", c.scaler.batchSize))
 	return nil
 }
 
+// Stop stops reading new messages, then lets workers drain queued batches until drainTimeout.
+// Batches still unfinished at the deadline stay unacknowledged in this consumer's pending list. A restarted
+// consumer with the same name only reads them again after one of its own batches fails; otherwise another
+// consumer claims them once this one has been idle for deadConsumerTimeout and its heartbeat has stopped.
 func (c *AbstractConsumer) Stop() {
 	logger.Info("Stopping consumer", zap.String("stream", c.config.StreamKey))
 	c.cancel()
-	c.wg.Wait()
-	logger.Info("Consumer stopped", zap.String("stream", c.config.StreamKey))
+	c.consumeWg.Wait()
+	c.scaler.unlimit()
+
+	drained := make(chan struct{})
+	go func() {
+		c.workerWg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(c.drainTimeout):
+		logger.Warn("Drain timeout exceeded, cancelling in-flight batches",
+			zap.String("stream", c.config.StreamKey),
+			zap.Duration("drain_timeout", c.drainTimeout))
+		c.processCancel()
+		<-drained
+	}
+	c.processCancel()
+
+	logger.Info("Consumer stopped",
+		zap.String("stream", c.config.StreamKey),
+		zap.Int64("abandoned_batches", c.abandonedBatches.Load()))
+}
+
+func (c *AbstractConsumer) Stats() Stats {
+	return Stats{
+		BatchesProcessed:  c.batchesProcessed.Load(),
+		MessagesProcessed: c.messagesProcessed.Load(),
+		BatchErrors:       c.batchErrors.Load(),
+		AckErrors:         c.ackErrors.Load(),
+		AbandonedBatches:  c.abandonedBatches.Load(),
+		DeadLettered:      c.deadLettered.Load(),
+	}
+}
+
+// LastRead is when the read loop last returned from the stream or last checked on the batches or workers it is
+// waiting for; it goes stale while the loop is stuck, e.g. in a read that never returns
+func (c *AbstractConsumer) LastRead() time.Time {
+	return time.Unix(0, c.lastRead.Load())
 }
 
 func (c *AbstractConsumer) createConsumerGroup() error {
@@ -99,75 +228,275 @@ func (c *AbstractConsumer) createConsumerGroup() error {
 	return nil
 }
 
-func (c *AbstractConsumer) consume(batchChan chan<- []redis.XMessage) {
-	defer c.wg.Done()
-	defer close(batchChan)
+// newBatchChannels returns one queue per worker for partitioned processors, otherwise a single shared queue
+func (c *AbstractConsumer) newBatchChannels() []chan []redis.XMessage {
+	if _, ok := c.processor.(Partitioner); !ok {
+		return []chan []redis.XMessage{make(chan []redis.XMessage, c.workerPool*2)}
+	}
 
-	for {
-		select {
-		case <-c.ctx.Done():
+	batchChans := make([]chan []redis.XMessage, c.workerPool)
+	for i := range batchChans {
+		batchChans[i] = make(chan []redis.XMessage, 2)
+	}
+	return batchChans
+}
+
+func (c *AbstractConsumer) consume(batchChans []chan []redis.XMessage) {
+	defer c.consumeWg.Done()
+
+	for c.ctx.Err() == nil {
+		if !c.waitForDownstream() {
 			return
-		default:
-			messages, err := c.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
-				Group:    c.config.Group,
-				Consumer: c.config.Consumer,
-				Streams:  []string{c.config.StreamKey, ">"},
-				Count:    int64(c.batchSize),
-				Block:    c.blockTime,
-			}).Result()
-
-			if err != nil {
-				if errors.Is(err, redis.Nil) {
-					continue
+		}
+
+		// Failed batches and entries claimed from dead consumers stay in this consumer's pending list. They are
+		// read again from its start once nothing is in flight, so no entry is processed twice at the same time.
+		startID := ">"
+		failures := c.failures()
+		if failures != c.retriedUpTo {
+			if c.inFlight.Load() > 0 {
+				// Waiting for in-flight batches on purpose is not a stuck read loop
+				c.lastRead.Store(time.Now().UnixNano())
+				if !c.sleep(inFlightPollInterval) {
+					return
 				}
-				logger.Error("Error reading from stream",
-					zap.String("stream", c.config.StreamKey),
-					logger.WithError(err))
-				time.Sleep(3 * time.Second)
 				continue
 			}
+			startID = "0"
+		}
 
-			var allMessages []redis.XMessage
-			for _, stream := range messages {
-				allMessages = append(allMessages, stream.Messages...)
+		messages, err := c.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+			Group:    c.config.Group,
+			Consumer: c.config.Consumer,
+			Streams:  []string{c.config.StreamKey, startID},
+			Count:    int64(c.scaler.batchSize),
+			Block:    c.blockTime,
+		}).Result()
+		c.lastRead.Store(time.Now().UnixNano())
+
+		if err != nil {
+			if errors.Is(err, redis.Nil) || c.ctx.Err() != nil {
+				continue
 			}
+			logger.Error("Error reading from stream",
+				zap.String("stream", c.config.StreamKey),
+				logger.WithError(err))
+			if !c.sleep(3 * time.Second) {
+				return
+			}
+			continue
+		}
 
-			if len(allMessages) > 0 {
-				select {
-				case batchChan <- allMessages:
-					logger.Debug("Sent batch to workers", zap.Int("batch_size", len(allMessages)))
-				case <-c.ctx.Done():
+		var allMessages []redis.XMessage
+		for _, stream := range messages {
+			allMessages = append(allMessages, stream.Messages...)
+		}
+		if startID == "0" {
+			if len(allMessages) == 0 {
+				c.retriedUpTo = failures
+				continue
+			}
+			// Entries trimmed from the stream come back without values and only need acknowledging
+			allMessages = slices.DeleteFunc(allMessages, func(msg redis.XMessage) bool {
+				return msg.Values == nil && c.acknowledgeMessage(msg.ID)
+			})
+			var ok bool
+			if allMessages, ok = c.dropExhausted(allMessages); !ok {
+				// Exhausted entries stay pending until the dead letter queue takes them
+				if !c.sleep(3 * time.Second) {
 					return
 				}
+				continue
 			}
+			logger.Info("Retrying pending messages",
+				zap.String("stream", c.config.StreamKey),
+				zap.Int("count", len(allMessages)))
+		}
+		c.scaler.observeRead(len(allMessages), queuedBatches(batchChans), time.Now())
+
+		if len(allMessages) > 0 && !c.dispatch(batchChans, allMessages) {
+			return
 		}
 	}
 }
 
+// dropExhausted moves messages delivered more than maxDeliveries times to the dead letter queue and
+// acknowledges them, so an entry that fails every time cannot keep the read loop from new messages.
+// It reports false if an exhausted message could not be moved; that message is left pending.
+func (c *AbstractConsumer) dropExhausted(messages []redis.XMessage) ([]redis.XMessage, bool) {
+	if len(messages) == 0 || c.maxDeliveries <= 0 {
+		return messages, true
+	}
+
+	pending, err := c.client.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+		Stream:   c.config.StreamKey,
+		Group:    c.config.Group,
+		Start:    messages[0].ID,
+		End:      messages[len(messages)-1].ID,
+		Count:    int64(len(messages)),
+		Consumer: c.config.Consumer,
+	}).Result()
+	if err != nil {
+		logger.Error("Error reading delivery counts",
+			zap.String("stream", c.config.StreamKey),
+			logger.WithError(err))
+		return nil, false
+	}
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		deliveries[p.ID] = p.RetryCount
+	}
+
+	moved := true
+	retry := messages[:0]
+	for _, msg := range messages {
+		count := deliveries[msg.ID]
+		if count <= c.maxDeliveries {
+			retry = append(retry, msg)
+			continue
+		}
+		reason := fmt.Errorf("processing failed on all %d deliveries", c.maxDeliveries)
+		if err := c.deadLetters.Send(c.ctx, c.config.StreamKey, msg, reason); err != nil {
+			moved = false
+			logger.Error("Error moving exhausted message to dead letter queue",
+				zap.String("stream", c.config.StreamKey),
+				zap.String("message_id", msg.ID),
+				logger.WithError(err))
+			continue
+		}
+		if c.acknowledgeMessage(msg.ID) {
+			c.deadLettered.Add(1)
+			logger.Warn("Moved message to dead letter queue after repeated failures",
+				zap.String("stream", c.config.StreamKey),
+				zap.String("message_id", msg.ID),
+				zap.Int64("deliveries", count))
+		}
+	}
+	return retry, moved
+}
+
+// waitForDownstream holds the next read while downstream is degraded: in-flight batches finish first, then the
+// backoff passes. Waiting on purpose keeps lastRead fresh. It reports false if the consumer stopped meanwhile.
+func (c *AbstractConsumer) waitForDownstream() bool {
+	if !c.backpressure.isDegraded() {
+		return true
+	}
+	for c.inFlight.Load() > 0 {
+		c.lastRead.Store(time.Now().UnixNano())
+		if !c.sleep(inFlightPollInterval) {
+			return false
+		}
+	}
+
+	wait := c.backpressure.pause()
+	if wait == 0 {
+		return true
+	}
+	logger.Debug("Pausing stream reads", zap.String("stream", c.config.StreamKey), zap.Duration("backoff", wait))
+	c.lastRead.Store(time.Now().UnixNano())
+	return c.sleep(wait)
+}
+
+func (c *AbstractConsumer) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// failures counts batches whose messages were left pending, either unprocessed or unacknowledged, and claims
+// of other consumers' pending entries
+func (c *AbstractConsumer) failures() int64 {
+	return c.batchErrors.Load() + c.ackErrors.Load() + c.claims.Load()
+}
+
+// dispatch hands a batch to the workers and reports false if the consumer stopped first.
+// With several queues the batch is split by partition key, keeping stream order inside each partition.
+func (c *AbstractConsumer) dispatch(batchChans []chan []redis.XMessage, messages []redis.XMessage) bool {
+	if len(batchChans) == 1 {
+		return c.send(batchChans[0], messages)
+	}
+
+	partitioner := c.processor.(Partitioner)
+	partitions := make([][]redis.XMessage, len(batchChans))
+	for _, msg := range messages {
+		idx := partitionIndex(partitioner.PartitionKey(msg), len(batchChans))
+		partitions[idx] = append(partitions[idx], msg)
+	}
+
+	for idx, partition := range partitions {
+		if len(partition) == 0 {
+			continue
+		}
+		if !c.send(batchChans[idx], partition) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *AbstractConsumer) send(batchChan chan<- []redis.XMessage, messages []redis.XMessage) bool {
+	ticker := time.NewTicker(inFlightPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case batchChan <- messages:
+			c.inFlight.Add(1)
+			logger.Debug("Sent batch to workers", zap.Int("batch_size", len(messages)))
+			return true
+		case <-ticker.C:
+			// Waiting for a worker to take the batch is not a stuck read loop
+			c.lastRead.Store(time.Now().UnixNano())
+		case <-c.ctx.Done():
+			c.abandonedBatches.Add(1)
+			return false
+		}
+	}
+}
+
+func queuedBatches(batchChans []chan []redis.XMessage) int {
+	queued := 0
+	for _, ch := range batchChans {
+		queued += len(ch)
+	}
+	return queued
+}
+
+func partitionIndex(key string, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
+
 func (c *AbstractConsumer) batchWorker(workerID int, batchChan <-chan []redis.XMessage) {
-	defer c.wg.Done()
+	defer c.workerWg.Done()
 	logger.Debug("Worker started",
 		zap.Int("worker_id", workerID),
 		zap.String("stream", c.config.StreamKey))
 
-	for {
-		select {
-		case <-c.ctx.Done():
-			logger.Debug("Worker stopping",
-				zap.Int("worker_id", workerID),
-				zap.String("stream", c.config.StreamKey))
-			return
-		case batch, ok := <-batchChan:
-			if !ok {
-				logger.Debug("Batch channel closed", zap.Int("worker_id", workerID))
-				return
-			}
-			logger.Debug("Worker processing batch",
-				zap.Int("worker_id", workerID),
-				zap.Int("batch_size", len(batch)))
-			c.processBatch(batch)
+	// Keep consuming until the channel is closed so queued batches are drained on shutdown
+	for batch := range batchChan {
+		if c.processCtx.Err() != nil {
+			c.abandonedBatches.Add(1)
+			c.inFlight.Add(-1)
+			continue
 		}
+		logger.Debug("Worker processing batch",
+			zap.Int("worker_id", workerID),
+			zap.Int("batch_size", len(batch)))
+		c.scaler.workers.acquire()
+		c.processBatch(batch)
+		c.scaler.workers.release()
+		c.inFlight.Add(-1)
 	}
+
+	logger.Debug("Worker stopping",
+		zap.Int("worker_id", workerID),
+		zap.String("stream", c.config.StreamKey))
 }
 
 func (c *AbstractConsumer) processBatch(messages []redis.XMessage) {
@@ -175,25 +504,77 @@ func (c *AbstractConsumer) processBatch(messages []redis.XMessage) {
 		return
 	}
 
-	err := c.processor.ProcessBatch(c.ctx, messages)
+	// Each message may carry its producer's trace; a batch has many, so they are linked rather than parents
+	var links []trace.Link
+	for _, msg := range messages {
+		if sc := tracing.RemoteSpanContext(msg.Values); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	ctx, span := tracing.Tracer().Start(c.processCtx, "consumer.process_batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", c.config.StreamKey),
+			attribute.String("messaging.consumer.group.name", c.config.Group),
+			attribute.Int("messaging.batch.message_count", len(messages)),
+		))
+	defer span.End()
+	ctx = context.WithValue(ctx, sourceStreamKey{}, c.config.StreamKey)
+
+	start := time.Now()
+	err := c.processor.ProcessBatch(ctx, messages)
+	duration := time.Since(start)
+	result := "success"
+	if err != nil {
+		result = "error"
+		tracing.RecordError(span, err)
+	}
+	metrics.BatchSize.WithLabelValues(c.config.StreamKey).Observe(float64(len(messages)))
+	metrics.BatchDuration.WithLabelValues(c.config.StreamKey, result).Observe(duration.Seconds())
+	c.scaler.observeBatch(duration)
+
+	// A batch interrupted by the drain deadline stays pending for redelivery
+	if c.processCtx.Err() != nil {
+		c.abandonedBatches.Add(1)
+		return
+	}
+
+	// A failed batch is not acknowledged; the read loop retries it from the pending list
 	if err != nil {
-		logger.Error("Error processing batch", logger.WithError(err))
+		c.batchErrors.Add(1)
+		c.backpressure.observe(duration, true)
+		logger.Error("Error processing batch, leaving messages pending",
+			zap.String("stream", c.config.StreamKey),
+			zap.Int("batch_size", len(messages)),
+			logger.WithError(err))
+		return
 	}
 
+	acked := true
 	for _, msg := range messages {
-		c.acknowledgeMessage(msg.ID)
+		acked = c.acknowledgeMessage(msg.ID) && acked
 	}
+	c.backpressure.observe(duration, !acked)
+	c.batchesProcessed.Add(1)
+	c.messagesProcessed.Add(int64(len(messages)))
 }
 
-func (c *AbstractConsumer) acknowledgeMessage(messageID string) {
+func (c *AbstractConsumer) acknowledgeMessage(messageID string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
 	if err := c.client.XAck(
-		c.ctx,
+		ctx,
 		c.config.StreamKey,
 		c.config.Group,
 		messageID,
 	).Err(); err != nil {
+		c.ackErrors.Add(1)
 		logger.Error("Error acknowledging message",
 			zap.String("message_id", messageID),
 			logger.WithError(err))
+		return false
 	}
+	return true
 }
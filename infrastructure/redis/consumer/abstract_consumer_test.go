@@ -0,0 +1,268 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+)
+
+// failingProcessor fails every batch that contains a poison message
+type failingProcessor struct {
+	processed    atomic.Int64
+	sourceStream atomic.Value
+}
+
+func (p *failingProcessor) ProcessBatch(ctx context.Context, messages []redis.XMessage) error {
+	p.sourceStream.Store(SourceStream(ctx))
+	for _, msg := range messages {
+		if msg.Values["poison"] == "1" {
+			return errors.New("poison message")
+		}
+	}
+	p.processed.Add(int64(len(messages)))
+	return nil
+}
+
+func TestConsumerMovesExhaustedMessagesToDeadLetterQueue(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	const stream, dlq, maxDeliveries = "test_stream", "test_dlq", 3
+	processor := &failingProcessor{}
+	c := NewAbstractConsumer(
+		client,
+		StreamConfig{StreamKey: stream, Group: "test_group", Consumer: "test_consumer"},
+		processor,
+		2,
+		10,
+		ScalingConfig{MinBatchSize: 1, MaxBatchSize: 10, MinWorkers: 1, MaxWorkers: 2},
+		BackpressureConfig{Window: 2, ErrorPercent: 50, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond},
+		NewDeadLetterQueue(client, dlq, 1000),
+		maxDeliveries,
+		20*time.Millisecond,
+		time.Second,
+	)
+
+	poisonID := client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"poison": "1"}}).Val()
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(c.Stop)
+
+	waitFor(t, "poison message to be dead lettered", func() bool {
+		return client.XLen(ctx, dlq).Val() == 1
+	})
+
+	for i := 0; i < 3; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"poison": "0"}})
+	}
+	waitFor(t, "new messages to be processed", func() bool {
+		return processor.processed.Load() == 3
+	})
+
+	entries := client.XRange(ctx, dlq, "-", "+").Val()
+	if got := entries[0].Values["dlq.sourceId"]; got != poisonID {
+		t.Errorf("dlq.sourceId = %v, want %s", got, poisonID)
+	}
+	if got := entries[0].Values["dlq.sourceStream"]; got != stream {
+		t.Errorf("dlq.sourceStream = %v, want %s", got, stream)
+	}
+	if pending := client.XPending(ctx, stream, "test_group").Val(); pending.Count != 0 {
+		t.Errorf("pending = %d, want 0", pending.Count)
+	}
+	if got := processor.sourceStream.Load(); got != stream {
+		t.Errorf("SourceStream = %v, want %s", got, stream)
+	}
+	stats := c.Stats()
+	if stats.DeadLettered != 1 {
+		t.Errorf("DeadLettered = %d, want 1", stats.DeadLettered)
+	}
+	if stats.BatchErrors != maxDeliveries {
+		t.Errorf("BatchErrors = %d, want %d", stats.BatchErrors, maxDeliveries)
+	}
+}
+
+// blockingProcessor holds batches with a slow message until released and fails batches with a poison message
+type blockingProcessor struct {
+	release chan struct{}
+}
+
+func (p *blockingProcessor) ProcessBatch(ctx context.Context, messages []redis.XMessage) error {
+	for _, msg := range messages {
+		switch {
+		case msg.Values["slow"] == "1":
+			select {
+			case <-p.release:
+			case <-ctx.Done():
+			}
+		case msg.Values["poison"] == "1":
+			return errors.New("poison message")
+		}
+	}
+	return nil
+}
+
+func TestConsumerStaysAliveWhileWaitingForInFlightBatches(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	const stream = "test_stream"
+	processor := &blockingProcessor{release: make(chan struct{})}
+	c := NewAbstractConsumer(
+		client,
+		StreamConfig{StreamKey: stream, Group: "test_group", Consumer: "test_consumer"},
+		processor,
+		2,
+		1,
+		ScalingConfig{MinBatchSize: 1, MaxBatchSize: 1, MinWorkers: 2, MaxWorkers: 2},
+		BackpressureConfig{Window: 1, ErrorPercent: 50, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond},
+		NewDeadLetterQueue(client, "test_dlq", 1000),
+		5,
+		20*time.Millisecond,
+		time.Second,
+	)
+
+	client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"slow": "1"}})
+	client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"poison": "1"}})
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(c.Stop)
+	t.Cleanup(func() { close(processor.release) })
+
+	// The failed batch makes the read loop wait for the slow one before it re-reads its pending entries
+	waitFor(t, "poison batch to fail", func() bool {
+		return c.Stats().BatchErrors > 0
+	})
+	time.Sleep(5 * inFlightPollInterval)
+
+	if age := time.Since(c.LastRead()); age > 2*inFlightPollInterval {
+		t.Errorf("LastRead is %s old while waiting for an in-flight batch", age)
+	}
+}
+
+func TestConsumerStaysAliveWhileWaitingForWorkers(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	const stream = "test_stream"
+	processor := &blockingProcessor{release: make(chan struct{})}
+	c := NewAbstractConsumer(
+		client,
+		StreamConfig{StreamKey: stream, Group: "test_group", Consumer: "test_consumer"},
+		processor,
+		1,
+		1,
+		ScalingConfig{MinBatchSize: 1, MaxBatchSize: 1, MinWorkers: 1, MaxWorkers: 1},
+		BackpressureConfig{},
+		NewDeadLetterQueue(client, "test_dlq", 1000),
+		5,
+		20*time.Millisecond,
+		time.Second,
+	)
+
+	// One batch is held by the worker and two fill the queue, so the read loop blocks handing over the fourth
+	for i := 0; i < 4; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"slow": "1"}})
+	}
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(c.Stop)
+	t.Cleanup(func() { close(processor.release) })
+
+	waitFor(t, "every message to be read", func() bool {
+		return client.XPending(ctx, stream, "test_group").Val().Count == 4
+	})
+	time.Sleep(5 * inFlightPollInterval)
+
+	if age := time.Since(c.LastRead()); age > 2*inFlightPollInterval {
+		t.Errorf("LastRead is %s old while waiting for a worker", age)
+	}
+}
+
+func TestConsumerReadsEntriesClaimedFromDeadConsumer(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	server := miniredis.RunT(t)
+	now := time.Now()
+	server.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+
+	const stream, group = "test_stream", "test_group"
+	processor := &failingProcessor{}
+	c := NewAbstractConsumer(
+		client,
+		StreamConfig{StreamKey: stream, Group: group, Consumer: "test_consumer"},
+		processor,
+		1,
+		10,
+		ScalingConfig{MinBatchSize: 1, MaxBatchSize: 10, MinWorkers: 1, MaxWorkers: 1},
+		BackpressureConfig{Window: 2, ErrorPercent: 50, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond},
+		NewDeadLetterQueue(client, "test_dlq", 1000),
+		3,
+		20*time.Millisecond,
+		time.Second,
+	)
+	if err := c.createConsumerGroup(); err != nil {
+		t.Fatalf("createConsumerGroup: %v", err)
+	}
+
+	// A replica reads an entry and goes away without a heartbeat
+	id := client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"poison": "0"}}).Val()
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: group, Consumer: "dead_consumer", Streams: []string{stream, ">"}})
+	server.SetTime(now.Add(2 * deadConsumerTimeout))
+
+	claimed, err := c.claimPending("dead_consumer")
+	if err != nil || claimed != 1 {
+		t.Fatalf("claimPending = %d, %v, want 1 entry", claimed, err)
+	}
+	pending := client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: stream, Group: group, Start: "-", End: "+", Count: 10}).Val()
+	if len(pending) != 1 || pending[0].ID != id || pending[0].Consumer != "test_consumer" {
+		t.Fatalf("pending = %+v, want %s claimed by test_consumer", pending, id)
+	}
+
+	// The claim only took ownership; the read loop reads the entry from its pending list
+	if processor.processed.Load() != 0 {
+		t.Fatal("claimed entry was processed before the read loop started")
+	}
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(c.Stop)
+	waitFor(t, "claimed entry to be processed", func() bool {
+		return client.XPending(ctx, stream, group).Val().Count == 0
+	})
+	if got := processor.processed.Load(); got != 1 {
+		t.Errorf("processed %d messages, want the claimed one once", got)
+	}
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
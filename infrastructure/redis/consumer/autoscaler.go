@@ -0,0 +1,169 @@
+package consumer
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+)
+
+const (
+	// scaleInterval is the minimum time between two adjustments, so one burst does not swing the limits back and forth
+	scaleInterval = 5 * time.Second
+	latencyWeight = 0.2
+)
+
+// ScalingConfig bounds the batch size and the number of workers processing batches at once
+type ScalingConfig struct {
+	MinBatchSize int
+	MaxBatchSize int
+	MinWorkers   int
+	MaxWorkers   int
+	// TargetBatchLatency is the average batch processing time above which batches are made smaller; zero disables it
+	TargetBatchLatency time.Duration
+}
+
+// workerLimiter caps how many workers process a batch at once. Workers for every partition keep running,
+// so the partition of a key never changes while the cap moves.
+type workerLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func newWorkerLimiter(limit int) *workerLimiter {
+	l := &workerLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *workerLimiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+func (l *workerLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.cond.Signal()
+}
+
+func (l *workerLimiter) setLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+func (l *workerLimiter) state() (active, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active, l.limit
+}
+
+// autoscaler grows the batch size and worker limit while reads come back full and batches queue up,
+// and shrinks them when the stream is quiet or batches take longer than the target latency.
+// Only the read loop calls observeRead and batchSize; workers report through observeBatch.
+type autoscaler struct {
+	config    ScalingConfig
+	stream    string
+	batchSize int
+	workers   *workerLimiter
+	lastScale time.Time
+
+	mu      sync.Mutex
+	latency time.Duration
+}
+
+func newAutoscaler(stream string, config ScalingConfig, batchSize, workers int) *autoscaler {
+	a := &autoscaler{
+		config:    config,
+		stream:    stream,
+		batchSize: min(max(batchSize, config.MinBatchSize), config.MaxBatchSize),
+		workers:   newWorkerLimiter(min(max(workers, config.MinWorkers), config.MaxWorkers)),
+	}
+	a.publish()
+	return a
+}
+
+// observeBatch folds a batch's processing time into the moving average latency
+func (a *autoscaler) observeBatch(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.latency == 0 {
+		a.latency = d
+		return
+	}
+	a.latency = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(a.latency))
+}
+
+func (a *autoscaler) averageLatency() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.latency
+}
+
+// observeRead adjusts the limits after a read returned read messages while queued batches waited for a worker
+func (a *autoscaler) observeRead(read, queued int, now time.Time) {
+	if now.Sub(a.lastScale) < scaleInterval {
+		return
+	}
+
+	batchSize := a.batchSize
+	latency := a.averageLatency()
+	switch {
+	case a.config.TargetBatchLatency > 0 && latency > a.config.TargetBatchLatency:
+		batchSize = max(batchSize/2, a.config.MinBatchSize)
+	case read >= batchSize:
+		batchSize = min(batchSize*2, a.config.MaxBatchSize)
+	case read < batchSize/4:
+		batchSize = max(batchSize/2, a.config.MinBatchSize)
+	}
+
+	// Scale up quickly to drain a burst, then give workers back one at a time
+	active, workers := a.workers.state()
+	switch {
+	case queued > 0 && active >= workers:
+		workers = min(workers*2, a.config.MaxWorkers)
+	case queued == 0 && active < workers/2:
+		workers = max(workers-1, a.config.MinWorkers)
+	}
+
+	_, currentWorkers := a.workers.state()
+	if batchSize == a.batchSize && workers == currentWorkers {
+		return
+	}
+	a.lastScale = now
+
+	logger.Debug("Consumer scaled",
+		zap.String("stream", a.stream),
+		zap.Int("batch_size", batchSize),
+		zap.Int("workers", workers),
+		zap.Int("read", read),
+		zap.Int("queued_batches", queued),
+		zap.Duration("avg_batch_latency", latency))
+	a.batchSize = batchSize
+	a.workers.setLimit(workers)
+	a.publish()
+}
+
+// unlimit lets every worker run, used while draining on shutdown
+func (a *autoscaler) unlimit() {
+	a.workers.setLimit(a.config.MaxWorkers)
+	a.publish()
+}
+
+func (a *autoscaler) publish() {
+	_, workers := a.workers.state()
+	metrics.ConsumerTargetBatchSize.WithLabelValues(a.stream).Set(float64(a.batchSize))
+	metrics.ConsumerWorkerLimit.WithLabelValues(a.stream).Set(float64(workers))
+}
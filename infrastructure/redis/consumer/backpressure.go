@@ -0,0 +1,136 @@
+package consumer
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+)
+
+// BackpressureConfig decides when the stores behind the processor count as degraded and how long reading pauses
+type BackpressureConfig struct {
+	// Window is the number of most recent batches judged; nothing is judged until it is full
+	Window int
+	// ErrorPercent is the share of failed batches in the window at which reading pauses; zero disables it
+	ErrorPercent int
+	// Latency is the average batch processing time in the window at which reading pauses; zero disables it
+	Latency        time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+type batchOutcome struct {
+	duration time.Duration
+	failed   bool
+}
+
+// backpressure tracks recent batch outcomes. Once degraded, the read loop pauses with a doubling backoff and
+// lets a single batch through after each pause; the first batch that succeeds in time ends the degradation.
+type backpressure struct {
+	config BackpressureConfig
+	stream string
+
+	mu       sync.Mutex
+	outcomes []batchOutcome
+	next     int
+	degraded bool
+	backoff  time.Duration
+}
+
+func newBackpressure(stream string, config BackpressureConfig) *backpressure {
+	metrics.ConsumerDegraded.WithLabelValues(stream).Set(0)
+	return &backpressure{
+		config:   config,
+		stream:   stream,
+		outcomes: make([]batchOutcome, 0, config.Window),
+	}
+}
+
+func (b *backpressure) observe(d time.Duration, failed bool) {
+	if b.config.Window <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.degraded {
+		if !failed && !b.slow(d) {
+			b.recover()
+		}
+		return
+	}
+
+	outcome := batchOutcome{duration: d, failed: failed}
+	if len(b.outcomes) < b.config.Window {
+		b.outcomes = append(b.outcomes, outcome)
+	} else {
+		b.outcomes[b.next] = outcome
+	}
+	b.next = (b.next + 1) % b.config.Window
+
+	if len(b.outcomes) == b.config.Window {
+		b.evaluate()
+	}
+}
+
+func (b *backpressure) evaluate() {
+	var failures int
+	var total time.Duration
+	for _, outcome := range b.outcomes {
+		total += outcome.duration
+		if outcome.failed {
+			failures++
+		}
+	}
+	errorPercent := failures * 100 / len(b.outcomes)
+	latency := total / time.Duration(len(b.outcomes))
+
+	if (b.config.ErrorPercent <= 0 || errorPercent < b.config.ErrorPercent) && !b.slow(latency) {
+		return
+	}
+
+	b.degraded = true
+	b.backoff = b.config.InitialBackoff
+	metrics.ConsumerDegraded.WithLabelValues(b.stream).Set(1)
+	logger.Warn("Downstream degraded, pausing stream reads",
+		zap.String("stream", b.stream),
+		zap.Int("error_percent", errorPercent),
+		zap.Duration("avg_batch_latency", latency),
+		zap.Duration("backoff", b.backoff))
+}
+
+func (b *backpressure) slow(d time.Duration) bool {
+	return b.config.Latency > 0 && d >= b.config.Latency
+}
+
+func (b *backpressure) recover() {
+	b.degraded = false
+	b.backoff = 0
+	b.outcomes = b.outcomes[:0]
+	b.next = 0
+	metrics.ConsumerDegraded.WithLabelValues(b.stream).Set(0)
+	logger.Info("Downstream recovered, resuming stream reads", zap.String("stream", b.stream))
+}
+
+func (b *backpressure) isDegraded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.degraded
+}
+
+// pause returns how long to wait before the next read, zero when healthy, and doubles the backoff for the next one
+func (b *backpressure) pause() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.degraded {
+		return 0
+	}
+
+	wait := b.backoff
+	b.backoff = min(b.backoff*2, b.config.MaxBackoff)
+	return wait
+}
@@ -0,0 +1,202 @@
+package consumer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+)
+
+const (
+	heartbeatInterval   = 10 * time.Second
+	reapInterval        = time.Minute
+	deadConsumerTimeout = 5 * time.Minute
+	claimBatchSize      = 100
+)
+
+// NewConsumerName derives a per-replica consumer name from the pod name (or hostname) plus a random suffix,
+// so replicas never share PEL ownership within a group
+func NewConsumerName(base string) string {
+	host := os.Getenv("POD_NAME")
+	if host == "" {
+		if h, err := os.Hostname(); err == nil {
+			host = h
+		}
+	}
+
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+
+	if host == "" {
+		return fmt.Sprintf("%s-%s", base, hex.EncodeToString(suffix))
+	}
+	return fmt.Sprintf("%s-%s-%s", base, host, hex.EncodeToString(suffix))
+}
+
+func (c *AbstractConsumer) membershipKey() string {
+	return fmt.Sprintf("stream:consumers:%s:%s", c.config.StreamKey, c.config.Group)
+}
+
+// maintainMembership keeps this consumer's heartbeat fresh and periodically takes over
+// pending entries of consumers whose replicas have gone away
+func (c *AbstractConsumer) maintainMembership() {
+	defer c.consumeWg.Done()
+	defer c.unregister()
+
+	c.heartbeat()
+
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+	reapTicker := time.NewTicker(reapInterval)
+	defer reapTicker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-heartbeatTicker.C:
+			c.heartbeat()
+		case <-reapTicker.C:
+			if err := c.reapDeadConsumers(); err != nil && c.ctx.Err() == nil {
+				logger.Error("Error reaping dead consumers",
+					zap.String("stream", c.config.StreamKey),
+					logger.WithError(err))
+			}
+		}
+	}
+}
+
+func (c *AbstractConsumer) heartbeat() {
+	if err := c.client.HSet(c.ctx, c.membershipKey(), c.config.Consumer, time.Now().UnixMilli()).Err(); err != nil && c.ctx.Err() == nil {
+		logger.Warn("Error sending consumer heartbeat",
+			zap.String("consumer", c.config.Consumer),
+			logger.WithError(err))
+	}
+}
+
+func (c *AbstractConsumer) unregister() {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	if err := c.client.HDel(ctx, c.membershipKey(), c.config.Consumer).Err(); err != nil {
+		logger.Warn("Error unregistering consumer",
+			zap.String("consumer", c.config.Consumer),
+			logger.WithError(err))
+	}
+}
+
+func (c *AbstractConsumer) reapDeadConsumers() error {
+	consumers, err := c.client.XInfoConsumers(c.ctx, c.config.StreamKey, c.config.Group).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list consumers: %w", err)
+	}
+
+	heartbeats, err := c.client.HGetAll(c.ctx, c.membershipKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read consumer heartbeats: %w", err)
+	}
+
+	now := time.Now()
+	for _, info := range consumers {
+		if info.Name == c.config.Consumer || info.Idle < deadConsumerTimeout {
+			continue
+		}
+		if lastSeen, ok := heartbeats[info.Name]; ok {
+			if ms, err := strconv.ParseInt(lastSeen, 10, 64); err == nil && now.Sub(time.UnixMilli(ms)) < deadConsumerTimeout {
+				continue
+			}
+		}
+
+		claimed, err := c.claimPending(info.Name)
+		if err != nil {
+			return err
+		}
+
+		// Only remove the consumer once its PEL is empty, otherwise those entries would be lost
+		pending, err := c.client.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+			Stream:   c.config.StreamKey,
+			Group:    c.config.Group,
+			Start:    "-",
+			End:      "+",
+			Count:    1,
+			Consumer: info.Name,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check pending entries of %s: %w", info.Name, err)
+		}
+		if len(pending) > 0 {
+			continue
+		}
+
+		if err := c.client.XGroupDelConsumer(c.ctx, c.config.StreamKey, c.config.Group, info.Name).Err(); err != nil {
+			return fmt.Errorf("failed to delete consumer %s: %w", info.Name, err)
+		}
+		if err := c.client.HDel(c.ctx, c.membershipKey(), info.Name).Err(); err != nil {
+			logger.Warn("Failed to remove heartbeat of dead consumer",
+				zap.String("stream", c.config.StreamKey),
+				zap.String("dead_consumer", info.Name),
+				logger.WithError(err))
+		}
+
+		logger.Info("Removed dead consumer",
+			zap.String("stream", c.config.StreamKey),
+			zap.String("dead_consumer", info.Name),
+			zap.Int("claimed_messages", claimed))
+	}
+	return nil
+}
+
+// claimPending moves a dead consumer's pending entries to this consumer's pending list. It only takes ownership:
+// the read loop picks them up from its pending list like failed batches, so they are never processed while
+// another batch of this consumer still holds them.
+func (c *AbstractConsumer) claimPending(deadConsumer string) (int, error) {
+	total := 0
+	for {
+		pending, err := c.client.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+			Stream:   c.config.StreamKey,
+			Group:    c.config.Group,
+			Start:    "-",
+			End:      "+",
+			Count:    claimBatchSize,
+			Consumer: deadConsumer,
+		}).Result()
+		if err != nil {
+			return total, fmt.Errorf("failed to list pending entries of %s: %w", deadConsumer, err)
+		}
+		if len(pending) == 0 {
+			return total, nil
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, p := range pending {
+			ids = append(ids, p.ID)
+		}
+
+		// JUSTID leaves the delivery counts alone; the read loop's own read counts as the next delivery
+		claimed, err := c.client.XClaimJustID(c.ctx, &redis.XClaimArgs{
+			Stream:   c.config.StreamKey,
+			Group:    c.config.Group,
+			Consumer: c.config.Consumer,
+			MinIdle:  deadConsumerTimeout,
+			Messages: ids,
+		}).Result()
+		if err != nil {
+			return total, fmt.Errorf("failed to claim entries of %s: %w", deadConsumer, err)
+		}
+		if len(claimed) == 0 {
+			// Another replica claimed them first
+			return total, nil
+		}
+
+		total += len(claimed)
+		c.claims.Add(1)
+	}
+}
@@ -0,0 +1,57 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"pomocore-data/domains/message"
+)
+
+// DeadLetterQueue stores messages that can never be processed, together with the reason, for later inspection
+type DeadLetterQueue struct {
+	client    redis.UniversalClient
+	streamKey string
+	maxLen    int64
+}
+
+func NewDeadLetterQueue(client redis.UniversalClient, streamKey string, maxLen int64) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		client:    client,
+		streamKey: streamKey,
+		maxLen:    maxLen,
+	}
+}
+
+func (q *DeadLetterQueue) Send(ctx context.Context, sourceStream string, msg redis.XMessage, reason error) error {
+	values := make(map[string]interface{}, len(msg.Values)+5)
+	for key, value := range msg.Values {
+		values[key] = value
+	}
+	values["dlq.sourceStream"] = sourceStream
+	values["dlq.sourceId"] = msg.ID
+	values["dlq.error"] = reason.Error()
+	values["dlq.failedAt"] = time.Now().Format(time.RFC3339)
+
+	var validationErr *message.ValidationError
+	if errors.As(reason, &validationErr) {
+		if fieldErrors, err := json.Marshal(validationErr.Errors); err == nil {
+			values["dlq.fieldErrors"] = string(fieldErrors)
+		}
+	}
+
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.streamKey,
+		MaxLen: q.maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to send message %s to dead letter queue: %w", msg.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"pomocore-data/infrastructure/redis/config"
+	"pomocore-data/shared/common/logger"
+)
+
+type namedConsumer struct {
+	name     string
+	consumer *AbstractConsumer
+}
+
+// Registry starts and stops several stream/processor pairs, each with its own AbstractConsumer
+type Registry struct {
+	client      redis.UniversalClient
+	deadLetters *DeadLetterQueue
+	processors  map[string]MessageProcessor
+	consumers   []namedConsumer
+}
+
+// NewRegistry creates a registry whose consumers move messages that keep failing to deadLetters
+func NewRegistry(client redis.UniversalClient, deadLetters *DeadLetterQueue) *Registry {
+	return &Registry{
+		client:      client,
+		deadLetters: deadLetters,
+		processors:  make(map[string]MessageProcessor),
+	}
+}
+
+// RegisterProcessor makes a processor available to pipelines configured under the given name
+func (r *Registry) RegisterProcessor(name string, processor MessageProcessor) {
+	r.processors[name] = processor
+}
+
+// Build creates a consumer for every configured pipeline
+func (r *Registry) Build(configs []config.ConsumerConfig) error {
+	for _, cfg := range configs {
+		processor, ok := r.processors[cfg.Name]
+		if !ok {
+			return fmt.Errorf("no processor registered for consumer %q", cfg.Name)
+		}
+
+		c := NewAbstractConsumer(
+			r.client,
+			StreamConfig{
+				StreamKey: cfg.Stream.StreamKey,
+				Group:     cfg.Stream.Group,
+				Consumer:  NewConsumerName(cfg.Stream.Consumer),
+			},
+			processor,
+			cfg.Workers,
+			cfg.BatchSize,
+			ScalingConfig{
+				MinBatchSize:       cfg.MinBatchSize,
+				MaxBatchSize:       cfg.MaxBatchSize,
+				MinWorkers:         cfg.MinWorkers,
+				MaxWorkers:         cfg.MaxWorkers,
+				TargetBatchLatency: cfg.TargetBatchLatency,
+			},
+			BackpressureConfig{
+				Window:         cfg.BackpressureWindow,
+				ErrorPercent:   cfg.BackpressureErrorPercent,
+				Latency:        cfg.BackpressureLatency,
+				InitialBackoff: cfg.BackpressureInitialBackoff,
+				MaxBackoff:     cfg.BackpressureMaxBackoff,
+			},
+			r.deadLetters,
+			cfg.MaxDeliveries,
+			cfg.BlockTime,
+			cfg.DrainTimeout,
+		)
+		r.consumers = append(r.consumers, namedConsumer{name: cfg.Name, consumer: c})
+	}
+	return nil
+}
+
+// StartAll starts every consumer; if one fails, the ones already started are stopped again
+func (r *Registry) StartAll() error {
+	for i, nc := range r.consumers {
+		if err := nc.consumer.Start(); err != nil {
+			for _, started := range r.consumers[:i] {
+				started.consumer.Stop()
+			}
+			return fmt.Errorf("failed to start consumer %q: %w", nc.name, err)
+		}
+	}
+	return nil
+}
+
+// StopAll drains all consumers in parallel so one slow pipeline does not eat the others' drain budget
+func (r *Registry) StopAll() {
+	var wg sync.WaitGroup
+	for _, nc := range r.consumers {
+		wg.Add(1)
+		go func(nc namedConsumer) {
+			defer wg.Done()
+			nc.consumer.Stop()
+		}(nc)
+	}
+	wg.Wait()
+	r.LogStats()
+}
+
+// Streams returns the stream configuration of every built consumer
+func (r *Registry) Streams() []StreamConfig {
+	streams := make([]StreamConfig, 0, len(r.consumers))
+	for _, nc := range r.consumers {
+		streams = append(streams, nc.consumer.config)
+	}
+	return streams
+}
+
+// CheckReadLoops fails when a consumer's read loop has not come back from the stream within maxAge
+func (r *Registry) CheckReadLoops(maxAge time.Duration) error {
+	for _, nc := range r.consumers {
+		if age := time.Since(nc.consumer.LastRead()); age > maxAge {
+			return fmt.Errorf("consumer %q has not read from %s for %s", nc.name, nc.consumer.config.StreamKey, age.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// CheckGroups fails when a consumer's group no longer exists on its stream
+func (r *Registry) CheckGroups(ctx context.Context) error {
+	for _, nc := range r.consumers {
+		groups, err := r.client.XInfoGroups(ctx, nc.consumer.config.StreamKey).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read groups of %s: %w", nc.consumer.config.StreamKey, err)
+		}
+		if !slices.ContainsFunc(groups, func(g redis.XInfoGroup) bool { return g.Name == nc.consumer.config.Group }) {
+			return fmt.Errorf("consumer group %q does not exist on %s", nc.consumer.config.Group, nc.consumer.config.StreamKey)
+		}
+	}
+	return nil
+}
+
+func (r *Registry) Stats() map[string]Stats {
+	stats := make(map[string]Stats, len(r.consumers))
+	for _, nc := range r.consumers {
+		stats[nc.name] = nc.consumer.Stats()
+	}
+	return stats
+}
+
+func (r *Registry) LogStats() {
+	for _, nc := range r.consumers {
+		stats := nc.consumer.Stats()
+		logger.Info("Consumer stats",
+			zap.String("consumer", nc.name),
+			zap.String("stream", nc.consumer.config.StreamKey),
+			zap.Int64("batches_processed", stats.BatchesProcessed),
+			zap.Int64("messages_processed", stats.MessagesProcessed),
+			zap.Int64("batch_errors", stats.BatchErrors),
+			zap.Int64("ack_errors", stats.AckErrors),
+			zap.Int64("abandoned_batches", stats.AbandonedBatches),
+			zap.Int64("dead_lettered", stats.DeadLettered))
+	}
+}
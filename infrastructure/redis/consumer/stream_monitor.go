@@ -0,0 +1,187 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+	"pomocore-data/shared/common/metrics"
+)
+
+const (
+	AlertMetricLag              = "lag"
+	AlertMetricPending          = "pending"
+	AlertMetricOldestPendingAge = "oldest_pending_age_seconds"
+
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// StreamMonitorThresholds are the levels above which a group's backlog is alerted; zero disables a threshold
+type StreamMonitorThresholds struct {
+	Lag              int64
+	Pending          int64
+	OldestPendingAge time.Duration
+}
+
+// StreamBacklog is a consumer group's position in its stream at one point in time
+type StreamBacklog struct {
+	Stream           string
+	Group            string
+	Length           int64
+	Lag              int64
+	Pending          int64
+	OldestPendingAge time.Duration
+}
+
+type StreamAlert struct {
+	Status    string  `json:"status"`
+	Stream    string  `json:"stream"`
+	Group     string  `json:"group"`
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// StreamAlertNotifier delivers threshold crossings to an external system
+type StreamAlertNotifier interface {
+	Notify(ctx context.Context, alert StreamAlert) error
+}
+
+// StreamMonitor reads the backlog of every consumed stream, publishes it as metrics and alerts when a
+// threshold is crossed or recovered. Alerts fire on transitions only, not on every check.
+type StreamMonitor struct {
+	client     redis.UniversalClient
+	streams    []StreamConfig
+	thresholds StreamMonitorThresholds
+	notifier   StreamAlertNotifier
+	firing     map[string]bool
+}
+
+// NewStreamMonitor monitors the groups of the registry's consumers; notifier may be nil to only log
+func NewStreamMonitor(client redis.UniversalClient, registry *Registry, thresholds StreamMonitorThresholds, notifier StreamAlertNotifier) *StreamMonitor {
+	return &StreamMonitor{
+		client:     client,
+		streams:    registry.Streams(),
+		thresholds: thresholds,
+		notifier:   notifier,
+		firing:     make(map[string]bool),
+	}
+}
+
+// Check is meant to run periodically; it is not safe for concurrent use
+func (m *StreamMonitor) Check(ctx context.Context) error {
+	var errs []error
+	for _, stream := range m.streams {
+		backlog, err := m.readBacklog(ctx, stream, time.Now())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		metrics.StreamLength.WithLabelValues(backlog.Stream).Set(float64(backlog.Length))
+		metrics.StreamLag.WithLabelValues(backlog.Stream, backlog.Group).Set(float64(backlog.Lag))
+		metrics.StreamPending.WithLabelValues(backlog.Stream, backlog.Group).Set(float64(backlog.Pending))
+		metrics.StreamOldestPendingAge.WithLabelValues(backlog.Stream, backlog.Group).Set(backlog.OldestPendingAge.Seconds())
+
+		m.evaluate(ctx, backlog, AlertMetricLag, float64(backlog.Lag), float64(m.thresholds.Lag))
+		m.evaluate(ctx, backlog, AlertMetricPending, float64(backlog.Pending), float64(m.thresholds.Pending))
+		m.evaluate(ctx, backlog, AlertMetricOldestPendingAge, backlog.OldestPendingAge.Seconds(), m.thresholds.OldestPendingAge.Seconds())
+	}
+	return errors.Join(errs...)
+}
+
+func (m *StreamMonitor) readBacklog(ctx context.Context, stream StreamConfig, now time.Time) (StreamBacklog, error) {
+	backlog := StreamBacklog{Stream: stream.StreamKey, Group: stream.Group}
+
+	info, err := m.client.XInfoStream(ctx, stream.StreamKey).Result()
+	if err != nil {
+		return backlog, fmt.Errorf("failed to read stream info of %s: %w", stream.StreamKey, err)
+	}
+	backlog.Length = info.Length
+
+	groups, err := m.client.XInfoGroups(ctx, stream.StreamKey).Result()
+	if err != nil {
+		return backlog, fmt.Errorf("failed to read groups of %s: %w", stream.StreamKey, err)
+	}
+	for _, group := range groups {
+		if group.Name != stream.Group {
+			continue
+		}
+		backlog.Pending = group.Pending
+		// Redis reports no lag once entries were deleted mid-stream; entries added minus entries read is then an upper bound
+		backlog.Lag = group.Lag
+		if backlog.Lag < 0 {
+			backlog.Lag = max(info.EntriesAdded-group.EntriesRead, 0)
+		}
+	}
+
+	if backlog.Pending > 0 {
+		pending, err := m.client.XPending(ctx, stream.StreamKey, stream.Group).Result()
+		if err != nil {
+			return backlog, fmt.Errorf("failed to read pending entries of %s: %w", stream.StreamKey, err)
+		}
+		if oldest, ok := streamIDTime(pending.Lower); ok {
+			backlog.OldestPendingAge = max(now.Sub(oldest), 0)
+		}
+	}
+	return backlog, nil
+}
+
+func (m *StreamMonitor) evaluate(ctx context.Context, backlog StreamBacklog, metric string, value, threshold float64) {
+	if threshold <= 0 {
+		return
+	}
+
+	key := backlog.Stream + "|" + backlog.Group + "|" + metric
+	exceeded := value > threshold
+	if exceeded == m.firing[key] {
+		return
+	}
+	m.firing[key] = exceeded
+
+	alert := StreamAlert{
+		Status:    AlertStatusResolved,
+		Stream:    backlog.Stream,
+		Group:     backlog.Group,
+		Metric:    metric,
+		Value:     value,
+		Threshold: threshold,
+	}
+	fields := []zap.Field{
+		zap.String("stream", alert.Stream),
+		zap.String("group", alert.Group),
+		zap.String("metric", alert.Metric),
+		zap.Float64("value", alert.Value),
+		zap.Float64("threshold", alert.Threshold),
+	}
+	if exceeded {
+		alert.Status = AlertStatusFiring
+		logger.Warn("Stream backlog threshold exceeded", fields...)
+	} else {
+		logger.Info("Stream backlog back under threshold", fields...)
+	}
+
+	if m.notifier != nil {
+		if err := m.notifier.Notify(ctx, alert); err != nil {
+			logger.Error("Failed to send stream alert", append(fields, logger.WithError(err))...)
+		}
+	}
+}
+
+// streamIDTime returns the time encoded in the millisecond part of a stream entry ID
+func streamIDTime(id string) (time.Time, bool) {
+	ms, _, _ := strings.Cut(id, "-")
+	v, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(v), true
+}
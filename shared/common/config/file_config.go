@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// LoadYAML decodes the YAML file at path over target, so keys the file leaves out keep target's values.
+// Unknown keys are rejected so a typo does not silently fall back to a default.
+func LoadYAML(path string, target any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Overrides applies environment variables on top of file values. Unlike GetEnv* it remembers values that
+// do not parse instead of falling back, so a mistyped variable fails startup.
+type Overrides struct {
+	errs []error
+}
+
+func (o *Overrides) String(key string, target *string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+	}
+}
+
+// Strings splits a comma separated variable, dropping empty items
+func (o *Overrides) Strings(key string, target *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (o *Overrides) Int(key string, target *int) {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %q is not an integer", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (o *Overrides) Int64(key string, target *int64) {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %q is not an integer", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (o *Overrides) Duration(key string, target *time.Duration) {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %q is not a duration like 30s or 5m", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (o *Overrides) Bool(key string, target *bool) {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %q is not a boolean", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+// Err reports every variable that failed to parse
+func (o *Overrides) Err() error {
+	return errors.Join(o.errs...)
+}
+
+// PrintYAML writes v as YAML with secrets masked. Fields tagged secret:"true" are replaced as a whole,
+// fields tagged secret:"url" only lose the password of the URL.
+func PrintYAML(w io.Writer, v any) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(redact(reflect.ValueOf(v)).Interface()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// redact returns a copy of v with secret fields masked, leaving v untouched
+func redact(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(redact(v.Elem()))
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			switch field.Tag.Get("secret") {
+			case "true":
+				if v.Field(i).String() != "" {
+					out.Field(i).SetString(redacted)
+				}
+			case "url":
+				out.Field(i).SetString(redactURL(v.Field(i).String()))
+			default:
+				out.Field(i).Set(redact(v.Field(i)))
+			}
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redact(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), redact(iter.Value()))
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func redactURL(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Cannot tell where the password is, so hide it all
+		return redacted
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
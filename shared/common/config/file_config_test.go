@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSection struct {
+	Name    string        `yaml:"name"`
+	Timeout time.Duration `yaml:"timeout"`
+	Tags    []string      `yaml:"tags"`
+}
+
+type testConfig struct {
+	Section  testSection  `yaml:"section"`
+	Password string       `yaml:"password" secret:"true"`
+	URL      string       `yaml:"url" secret:"url"`
+	Nested   *testSecrets `yaml:"nested"`
+	List     []testSecrets
+}
+
+type testSecrets struct {
+	Token string `yaml:"token" secret:"true"`
+	Note  string `yaml:"note"`
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    testSection
+		wantErr string
+	}{
+		{
+			name:    "keys left out keep their values",
+			content: "section:\n  timeout: 5s\n",
+			want:    testSection{Name: "default", Timeout: 5 * time.Second, Tags: []string{"a"}},
+		},
+		{
+			name:    "empty file",
+			content: "",
+			want:    testSection{Name: "default", Timeout: time.Second, Tags: []string{"a"}},
+		},
+		{
+			name:    "unknown key",
+			content: "section:\n  timeuot: 5s\n",
+			wantErr: "field timeuot not found",
+		},
+		{
+			name:    "malformed duration",
+			content: "section:\n  timeout: soon\n",
+			wantErr: "failed to parse config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &testConfig{Section: testSection{Name: "default", Timeout: time.Second, Tags: []string{"a"}}}
+			err := LoadYAML(writeFile(t, tt.content), cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadYAML: %v", err)
+			}
+			if cfg.Section.Name != tt.want.Name || cfg.Section.Timeout != tt.want.Timeout || !slices.Equal(cfg.Section.Tags, tt.want.Tags) {
+				t.Errorf("section = %+v, want %+v", cfg.Section, tt.want)
+			}
+		})
+	}
+
+	if err := LoadYAML(filepath.Join(t.TempDir(), "missing.yaml"), &testConfig{}); err == nil {
+		t.Error("loading a missing file succeeded")
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	t.Setenv("TEST_STRING", "value")
+	t.Setenv("TEST_STRINGS", " a, ,b ,")
+	t.Setenv("TEST_INT", "42")
+	t.Setenv("TEST_INT64", "9000000000")
+	t.Setenv("TEST_DURATION", "90s")
+	t.Setenv("TEST_BOOL", "false")
+	t.Setenv("TEST_EMPTY", "")
+
+	env := &Overrides{}
+	str, empty := "default", "default"
+	strs := []string{"default"}
+	i, i64 := 1, int64(1)
+	d := time.Second
+	b := true
+	env.String("TEST_STRING", &str)
+	env.String("TEST_EMPTY", &empty)
+	env.Strings("TEST_STRINGS", &strs)
+	env.Int("TEST_INT", &i)
+	env.Int64("TEST_INT64", &i64)
+	env.Duration("TEST_DURATION", &d)
+	env.Bool("TEST_BOOL", &b)
+
+	if err := env.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if str != "value" || empty != "default" || !slices.Equal(strs, []string{"a", "b"}) ||
+		i != 42 || i64 != 9000000000 || d != 90*time.Second || b {
+		t.Errorf("got %q %q %v %d %d %s %v", str, empty, strs, i, i64, d, b)
+	}
+}
+
+func TestOverridesReportEveryParseError(t *testing.T) {
+	t.Setenv("TEST_INT", "ten")
+	t.Setenv("TEST_INT64", "1.5")
+	t.Setenv("TEST_DURATION", "5")
+	t.Setenv("TEST_BOOL", "yes please")
+
+	env := &Overrides{}
+	i, i64 := 1, int64(1)
+	d := time.Second
+	b := true
+	env.Int("TEST_INT", &i)
+	env.Int64("TEST_INT64", &i64)
+	env.Duration("TEST_DURATION", &d)
+	env.Bool("TEST_BOOL", &b)
+
+	err := env.Err()
+	if err == nil {
+		t.Fatal("Err = nil, want every parse error")
+	}
+	for _, key := range []string{"TEST_INT:", "TEST_INT64:", "TEST_DURATION:", "TEST_BOOL:"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not mention %s", err, key)
+		}
+	}
+	if i != 1 || i64 != 1 || d != time.Second || !b {
+		t.Errorf("targets changed to %d %d %s %v, want them untouched", i, i64, d, b)
+	}
+}
+
+func TestPrintYAMLRedactsSecrets(t *testing.T) {
+	cfg := &testConfig{
+		Section:  testSection{Name: "visible"},
+		Password: "hunter2",
+		URL:      "mongodb://app:s3cret@db:27017/pomocore",
+		Nested:   &testSecrets{Token: "nested-token", Note: "kept"},
+		List:     []testSecrets{{Token: "list-token"}, {}},
+	}
+
+	var out strings.Builder
+	if err := PrintYAML(&out, cfg); err != nil {
+		t.Fatalf("PrintYAML: %v", err)
+	}
+	printed := out.String()
+
+	for _, secret := range []string{"hunter2", "s3cret", "nested-token", "list-token"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("output contains %q:\n%s", secret, printed)
+		}
+	}
+	for _, visible := range []string{"visible", "kept", "mongodb://app:REDACTED@db:27017/pomocore", "token: \"\""} {
+		if !strings.Contains(printed, visible) {
+			t.Errorf("output does not contain %q:\n%s", visible, printed)
+		}
+	}
+	if cfg.Password != "hunter2" || cfg.Nested.Token != "nested-token" || cfg.List[0].Token != "list-token" {
+		t.Error("PrintYAML changed the printed configuration")
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := map[string]string{
+		"":                                    "",
+		"redis://localhost:6379":              "redis://localhost:6379",
+		"mongodb://app@db:27017":              "mongodb://app@db:27017",
+		"mongodb://app:pw@db:27017/?ssl=true": "mongodb://app:REDACTED@db:27017/?ssl=true",
+		"https://hooks.example.com/x?y=1":     "https://hooks.example.com/x?y=1",
+		"://not a url":                        redacted,
+	}
+	for in, want := range tests {
+		if got := redactURL(in); got != want {
+			t.Errorf("redactURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
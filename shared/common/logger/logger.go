@@ -1,7 +1,6 @@
 package logger
 
 import (
-	"os"
 	"time"
 
 	"go.uber.org/zap"
@@ -132,12 +131,3 @@ func Fatalln(args ...interface{}) {
 func Sync() error {
 	return Logger.Sync()
 }
-
-// 환경 변수에서 로그 설정 초기화
-func InitFromEnv(serviceName string) error {
-	env := os.Getenv("APP_ENV")
-	if env == "" {
-		env = "production"
-	}
-	return InitLogger(serviceName, env)
-}
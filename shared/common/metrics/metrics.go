@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pomocore"
+
+// Classification sources, in the order the classifier tries them
+const (
+	SourceAppTrie = "app_trie"
+	SourceURL     = "url"
+	SourceCache   = "cache"
+	SourceLLM     = "llm"
+	SourceFailed  = "failed"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	BatchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "batch_size",
+		Help:      "Number of messages in each processed batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"stream"})
+
+	BatchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "batch_duration_seconds",
+		Help:      "Time spent processing a batch, by outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"stream", "result"})
+
+	ConsumerTargetBatchSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "target_batch_size",
+		Help:      "Number of messages the consumer currently asks for per read.",
+	}, []string{"stream"})
+
+	ConsumerWorkerLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "worker_limit",
+		Help:      "Number of workers currently allowed to process batches at once.",
+	}, []string{"stream"})
+
+	ConsumerDegraded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "degraded",
+		Help:      "1 while the consumer pauses reads because downstream batches fail or are slow, otherwise 0.",
+	}, []string{"stream"})
+
+	Classifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "classifier",
+		Name:      "classifications_total",
+		Help:      "Usage classifications by the source that produced the category.",
+	}, []string{"source"})
+
+	LLMRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of each LLM completion request, retries included as separate requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	})
+
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "Tokens consumed by LLM completions.",
+	}, []string{"type"})
+
+	LLMErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "errors_total",
+		Help:      "Failed LLM completion requests.",
+	})
+
+	MongoBulkWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "bulk_write_duration_seconds",
+		Help:      "Latency of MongoDB bulk writes.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"collection", "operation"})
+
+	MongoBulkWriteModified = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "bulk_write_modified_total",
+		Help:      "Documents modified or upserted by MongoDB bulk writes.",
+	}, []string{"collection", "operation"})
+
+	LeaderboardErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "leaderboard",
+		Name:      "errors_total",
+		Help:      "Failed leaderboard cache operations.",
+	}, []string{"operation"})
+
+	OutboxParked = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "parked_total",
+		Help:      "Session score events given up on after every publish attempt failed.",
+	})
+
+	StreamLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "length",
+		Help:      "Number of entries in the stream.",
+	}, []string{"stream"})
+
+	StreamLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "lag",
+		Help:      "Entries not yet delivered to the consumer group.",
+	}, []string{"stream", "group"})
+
+	StreamPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "pending",
+		Help:      "Entries delivered to the consumer group but not acknowledged.",
+	}, []string{"stream", "group"})
+
+	StreamOldestPendingAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "oldest_pending_age_seconds",
+		Help:      "Age of the oldest unacknowledged entry of the consumer group, 0 when none is pending.",
+	}, []string{"stream", "group"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		BatchSize,
+		BatchDuration,
+		ConsumerTargetBatchSize,
+		ConsumerWorkerLimit,
+		ConsumerDegraded,
+		Classifications,
+		LLMRequestDuration,
+		LLMTokens,
+		LLMErrors,
+		MongoBulkWriteDuration,
+		MongoBulkWriteModified,
+		LeaderboardErrors,
+		OutboxParked,
+		StreamLength,
+		StreamLag,
+		StreamPending,
+		StreamOldestPendingAge,
+	)
+}
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"pomocore-data/shared/common/logger"
+)
+
+// PeriodicTask runs a function on a fixed interval until stopped
+type PeriodicTask struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewPeriodicTask(name string, interval time.Duration, run func(ctx context.Context) error) *PeriodicTask {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PeriodicTask{
+		name:     name,
+		interval: interval,
+		run:      run,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (t *PeriodicTask) Start() {
+	t.wg.Add(1)
+	go t.loop()
+
+	logger.Info("Periodic task started",
+		zap.String("task", t.name),
+		zap.Duration("interval", t.interval))
+}
+
+func (t *PeriodicTask) Stop() {
+	t.cancel()
+	t.wg.Wait()
+	logger.Info("Periodic task stopped", zap.String("task", t.name))
+}
+
+func (t *PeriodicTask) loop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.run(t.ctx); err != nil && t.ctx.Err() == nil {
+				logger.Error("Periodic task failed",
+					zap.String("task", t.name),
+					logger.WithError(err))
+			}
+		}
+	}
+}